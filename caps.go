@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var capabilityNumbers = map[string]int{
+	"CAP_CHOWN":              unix.CAP_CHOWN,
+	"CAP_DAC_OVERRIDE":       unix.CAP_DAC_OVERRIDE,
+	"CAP_DAC_READ_SEARCH":    unix.CAP_DAC_READ_SEARCH,
+	"CAP_FOWNER":             unix.CAP_FOWNER,
+	"CAP_FSETID":             unix.CAP_FSETID,
+	"CAP_KILL":               unix.CAP_KILL,
+	"CAP_SETGID":             unix.CAP_SETGID,
+	"CAP_SETUID":             unix.CAP_SETUID,
+	"CAP_SETPCAP":            unix.CAP_SETPCAP,
+	"CAP_LINUX_IMMUTABLE":    unix.CAP_LINUX_IMMUTABLE,
+	"CAP_NET_BIND_SERVICE":   unix.CAP_NET_BIND_SERVICE,
+	"CAP_NET_BROADCAST":      unix.CAP_NET_BROADCAST,
+	"CAP_NET_ADMIN":          unix.CAP_NET_ADMIN,
+	"CAP_NET_RAW":            unix.CAP_NET_RAW,
+	"CAP_IPC_LOCK":           unix.CAP_IPC_LOCK,
+	"CAP_IPC_OWNER":          unix.CAP_IPC_OWNER,
+	"CAP_SYS_MODULE":         unix.CAP_SYS_MODULE,
+	"CAP_SYS_RAWIO":          unix.CAP_SYS_RAWIO,
+	"CAP_SYS_CHROOT":         unix.CAP_SYS_CHROOT,
+	"CAP_SYS_PTRACE":         unix.CAP_SYS_PTRACE,
+	"CAP_SYS_PACCT":          unix.CAP_SYS_PACCT,
+	"CAP_SYS_ADMIN":          unix.CAP_SYS_ADMIN,
+	"CAP_SYS_BOOT":           unix.CAP_SYS_BOOT,
+	"CAP_SYS_NICE":           unix.CAP_SYS_NICE,
+	"CAP_SYS_RESOURCE":       unix.CAP_SYS_RESOURCE,
+	"CAP_SYS_TIME":           unix.CAP_SYS_TIME,
+	"CAP_SYS_TTY_CONFIG":     unix.CAP_SYS_TTY_CONFIG,
+	"CAP_MKNOD":              unix.CAP_MKNOD,
+	"CAP_LEASE":              unix.CAP_LEASE,
+	"CAP_AUDIT_WRITE":        unix.CAP_AUDIT_WRITE,
+	"CAP_AUDIT_CONTROL":      unix.CAP_AUDIT_CONTROL,
+	"CAP_SETFCAP":            unix.CAP_SETFCAP,
+	"CAP_MAC_OVERRIDE":       unix.CAP_MAC_OVERRIDE,
+	"CAP_MAC_ADMIN":          unix.CAP_MAC_ADMIN,
+	"CAP_SYSLOG":             unix.CAP_SYSLOG,
+	"CAP_WAKE_ALARM":         unix.CAP_WAKE_ALARM,
+	"CAP_BLOCK_SUSPEND":      unix.CAP_BLOCK_SUSPEND,
+	"CAP_AUDIT_READ":         unix.CAP_AUDIT_READ,
+	"CAP_PERFMON":            unix.CAP_PERFMON,
+	"CAP_BPF":                unix.CAP_BPF,
+	"CAP_CHECKPOINT_RESTORE": unix.CAP_CHECKPOINT_RESTORE,
+}
+
+// defaultCapabilities is the set docker grants to containers
+var defaultCapabilities = []string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_FSETID",
+	"CAP_FOWNER",
+	"CAP_MKNOD",
+	"CAP_NET_RAW",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETFCAP",
+	"CAP_SETPCAP",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_SYS_CHROOT",
+	"CAP_KILL",
+	"CAP_AUDIT_WRITE",
+}
+
+func normalizeCapabilityName(name string) (string, error) {
+	name = strings.ToUpper(name)
+	if name == "ALL" {
+		return name, nil
+	}
+	if !strings.HasPrefix(name, "CAP_") {
+		name = "CAP_" + name
+	}
+	if _, ok := capabilityNumbers[name]; !ok {
+		return "", fmt.Errorf("unknown capability: %s", name)
+	}
+	return name, nil
+}
+
+func getAllCapabilities() []string {
+	var caps []string
+	for name := range capabilityNumbers {
+		caps = append(caps, name)
+	}
+	return caps
+}
+
+// resolveCapabilities applies --cap-drop and then --cap-add to the default set,
+// ALL in either list stands for every capability.
+func resolveCapabilities(capAdd []string, capDrop []string, privileged bool) ([]string, error) {
+	if privileged {
+		caps := getAllCapabilities()
+		sort.Strings(caps)
+		return caps, nil
+	}
+	capSet := map[string]bool{}
+	for _, name := range defaultCapabilities {
+		capSet[name] = true
+	}
+	for _, name := range capDrop {
+		name, err := normalizeCapabilityName(name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "ALL" {
+			capSet = map[string]bool{}
+		} else {
+			delete(capSet, name)
+		}
+	}
+	for _, name := range capAdd {
+		name, err := normalizeCapabilityName(name)
+		if err != nil {
+			return nil, err
+		}
+		if name == "ALL" {
+			for _, name := range getAllCapabilities() {
+				capSet[name] = true
+			}
+		} else {
+			capSet[name] = true
+		}
+	}
+	caps := []string{}
+	for name := range capSet {
+		caps = append(caps, name)
+	}
+	sort.Strings(caps)
+	return caps, nil
+}
+
+func getLastCapability() int {
+	data, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return unix.CAP_LAST_CAP
+	}
+	lastCap, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return unix.CAP_LAST_CAP
+	}
+	return lastCap
+}
+
+// getCapabilityMask skips capabilities the running kernel does not know
+func getCapabilityMask(caps []string) uint64 {
+	var mask uint64
+	lastCap := getLastCapability()
+	for _, name := range caps {
+		if num, ok := capabilityNumbers[name]; ok && num <= lastCap {
+			mask |= 1 << uint(num)
+		}
+	}
+	return mask
+}
+
+func getThreadCapabilities() ([2]unix.CapUserData, error) {
+	var data [2]unix.CapUserData
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	err := unix.Capget(&header, &data[0])
+	return data, err
+}
+
+func setThreadCapabilities(data [2]unix.CapUserData) error {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	return unix.Capset(&header, &data[0])
+}
+
+// limitCapabilities drops everything outside caps from the bounding and
+// ambient sets of the calling thread and makes caps its inheritable set. The
+// effective and permitted sets are left alone so the caller can still switch
+// users; with keepCaps they also survive a switch away from root. Callers
+// must be locked to their OS thread, capabilities are per thread.
+func limitCapabilities(caps []string, keepCaps bool) error {
+	mask := getCapabilityMask(caps)
+	for num := 0; num <= getLastCapability(); num++ {
+		if mask&(1<<uint(num)) != 0 {
+			continue
+		}
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(num), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("drop capability %d from bounding set: %v", num, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil && err != unix.EINVAL {
+		return err
+	}
+	data, err := getThreadCapabilities()
+	if err != nil {
+		return err
+	}
+	data[0].Inheritable = uint32(mask) & data[0].Permitted
+	data[1].Inheritable = uint32(mask>>32) & data[1].Permitted
+	if err := setThreadCapabilities(data); err != nil {
+		return err
+	}
+	if keepCaps {
+		return unix.Prctl(unix.PR_SET_KEEPCAPS, 1, 0, 0, 0)
+	}
+	return nil
+}
+
+// applyCapabilities sets the effective, permitted and inheritable sets of the
+// calling thread to caps once the container user is in place. Capabilities
+// gocker itself does not hold are left out.
+func applyCapabilities(caps []string) error {
+	mask := getCapabilityMask(caps)
+	data, err := getThreadCapabilities()
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		data[i].Permitted &= uint32(mask >> (32 * uint(i)))
+		data[i].Effective = data[i].Permitted
+		data[i].Inheritable = data[i].Permitted
+	}
+	if err := setThreadCapabilities(data); err != nil {
+		return err
+	}
+	return unix.Prctl(unix.PR_SET_KEEPCAPS, 0, 0, 0, 0)
+}
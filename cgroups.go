@@ -1,20 +1,40 @@
 package main
 
 import (
+	"golang.org/x/sys/unix"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 )
 
+// canManageCGroups reports whether the gocker cgroups can be written, in
+// rootless mode only when they were delegated to the user.
+func canManageCGroups() bool {
+	if !isRootless() {
+		return true
+	}
+	for _, controller := range []string{"memory", "pids", "cpu"} {
+		dir := "/sys/fs/cgroup/" + controller + "/gocker"
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			dir = filepath.Dir(dir)
+		}
+		if unix.Access(dir, unix.W_OK) != nil {
+			return false
+		}
+	}
+	return true
+}
+
 func removeCGroups(containerId string) {
 	cgroups := []string{"/sys/fs/cgroup/memory/gocker/" + containerId,
 		"/sys/fs/cgroup/pids/gocker/" + containerId,
 		"/sys/fs/cgroup/cpu/gocker/" + containerId}
 
 	for _, dir := range cgroups {
-		if err := os.Remove(dir); err != nil {
-			log.Fatalf("remove container cgroup dir failed %v", err)
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			log.Printf("remove container cgroup dir failed %v", err)
 		}
 	}
 }
@@ -68,7 +88,7 @@ func configureCGroups(containerId string, mem int, swap int, pids int, cpus floa
 	}
 }
 
-func createCGroups(containerId string, createCGroupDirs bool) {
+func createCGroups(containerId string, pid int, createCGroupDirs bool) {
 	cgroups := []string{"/sys/fs/cgroup/memory/gocker/" + containerId,
 		"/sys/fs/cgroup/pids/gocker/" + containerId,
 		"/sys/fs/cgroup/cpu/gocker/" + containerId}
@@ -82,7 +102,7 @@ func createCGroups(containerId string, createCGroupDirs bool) {
 		if err := os.WriteFile(cgroupDir+"/notify_on_release", []byte("1"), 0700); err != nil {
 			log.Fatalln("write cgroup file failed")
 		}
-		if err := os.WriteFile(cgroupDir+"/cgroup.procs", []byte(strconv.Itoa(os.Getpid())), 0700); err != nil {
+		if err := os.WriteFile(cgroupDir+"/cgroup.procs", []byte(strconv.Itoa(pid)), 0700); err != nil {
 			log.Fatalln("write cgroup file failed")
 		}
 	}
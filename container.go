@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	containerStatusCreated = "created"
+	containerStatusRunning = "running"
+	containerStatusExited  = "exited"
+)
+
+type containerConfig struct {
+	Image             string            `json:"image"`
+	ImageId           string            `json:"imageId"`
+	Args              []string          `json:"args"`
+	Entrypoint        *string           `json:"entrypoint,omitempty"`
+	Env               []string          `json:"env"`
+	User              string            `json:"user"`
+	Name              string            `json:"name"`
+	Hostname          string            `json:"hostname"`
+	Mounts            []mountConfig     `json:"mounts"`
+	ShmSize           int64             `json:"shmSize"`
+	ReadOnly          bool              `json:"readOnly"`
+	RootfsPropagation string            `json:"rootfsPropagation"`
+	Capabilities      []string          `json:"capabilities"`
+	Privileged        bool              `json:"privileged"`
+	SeccompProfile    string            `json:"seccompProfile,omitempty"`
+	MaskedPaths       []string          `json:"maskedPaths,omitempty"`
+	ReadonlyPaths     []string          `json:"readonlyPaths,omitempty"`
+	NoNewPrivileges   bool              `json:"noNewPrivileges"`
+	Ulimits           []ulimit          `json:"ulimits,omitempty"`
+	OOMScoreAdj       *int              `json:"oomScoreAdj,omitempty"`
+	Sysctls           map[string]string `json:"sysctls,omitempty"`
+	UidMappings       []idMapping       `json:"uidMappings,omitempty"`
+	GidMappings       []idMapping       `json:"gidMappings,omitempty"`
+	Mem               int               `json:"mem"`
+	Swap              int               `json:"swap"`
+	Pids              int               `json:"pids"`
+	Cpus              float64           `json:"cpus"`
+	Tty               bool              `json:"tty"`
+	Interactive       bool              `json:"interactive"`
+	Init              bool              `json:"init"`
+	Detach            bool              `json:"detach"`
+	AutoRemove        bool              `json:"autoRemove"`
+}
+
+type containerState struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Image   string   `json:"image"`
+	ImageId string   `json:"imageId"`
+	Command []string `json:"command"`
+	Pid     int      `json:"pid"`
+	// SupervisorPid is the gocker process that starts and waits for the
+	// container, the shim of a detached one
+	SupervisorPid int       `json:"supervisorPid,omitempty"`
+	Status        string    `json:"status"`
+	ExitCode      int       `json:"exitCode"`
+	CreatedAt     time.Time `json:"createdAt"`
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+}
+
+func isContainerRunning(state containerState) bool {
+	return state.Status == containerStatusRunning && isProcessRunning(state.Pid)
+}
+
+// isContainerSupervised tells whether the process that starts a container and
+// records how it exits is still there, a created container whose supervisor
+// died will never run
+func isContainerSupervised(state containerState) bool {
+	return state.Status != containerStatusExited && isProcessRunning(state.SupervisorPid)
+}
+
+// resolveContainerId accepts a full container ID, a container name or an
+// unambiguous prefix of a container ID.
+func resolveContainerId(ref string) (string, error) {
+	states, err := getContainerStates()
+	if err != nil {
+		return "", err
+	}
+	for _, state := range states {
+		if state.Id == ref || state.Name == ref {
+			return state.Id, nil
+		}
+	}
+	var matches []string
+	for _, state := range states {
+		if len(ref) > 0 && strings.HasPrefix(state.Id, ref) {
+			matches = append(matches, state.Id)
+		}
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("multiple containers match prefix %s", ref)
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no such container: %s", ref)
+	}
+	return matches[0], nil
+}
+
+func resolveContainerIdOrDie(ref string) string {
+	containerId, err := resolveContainerId(ref)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return containerId
+}
+
+func getContainerHome(containerId string) string {
+	return getGockerContainersPath() + "/" + containerId
+}
+
+func getContainerConfigPath(containerId string) string {
+	return getContainerHome(containerId) + "/config.json"
+}
+
+func saveContainerConfig(containerId string, config containerConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getContainerConfigPath(containerId), data, 0644)
+}
+
+func loadContainerConfig(containerId string) (containerConfig, error) {
+	config := containerConfig{}
+	data, err := os.ReadFile(getContainerConfigPath(containerId))
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+func getContainerStatePath(containerId string) string {
+	return getContainerHome(containerId) + "/state.json"
+}
+
+func saveContainerState(state containerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	statePath := getContainerStatePath(state.Id)
+	if err := os.WriteFile(statePath+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(statePath+".tmp", statePath)
+}
+
+func loadContainerState(containerId string) (containerState, error) {
+	state := containerState{}
+	data, err := os.ReadFile(getContainerStatePath(containerId))
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+func getContainerStates() ([]containerState, error) {
+	var states []containerState
+	entries, err := os.ReadDir(getGockerContainersPath())
+	if err != nil {
+		return states, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if state, err := loadContainerState(entry.Name()); err == nil {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+func updateContainerState(containerId string, update func(state *containerState)) error {
+	state, err := loadContainerState(containerId)
+	if err != nil {
+		return err
+	}
+	update(&state)
+	return saveContainerState(state)
+}
+
+func inspectContainer(containerId string) {
+	state, err := loadContainerState(containerId)
+	doOrDieWithMsg(err, "load container state failed")
+	config, err := loadContainerConfig(containerId)
+	doOrDieWithMsg(err, "load container config failed")
+	data, err := json.MarshalIndent(struct {
+		State  containerState  `json:"state"`
+		Config containerConfig `json:"config"`
+	}{state, config}, "", "  ")
+	doOrDieWithMsg(err, "marshal container details failed")
+	fmt.Println(string(data))
+}
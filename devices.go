@@ -0,0 +1,71 @@
+package main
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+)
+
+type containerDevice struct {
+	path  string
+	major uint32
+	minor uint32
+	mode  uint32
+}
+
+var defaultContainerDevices = []containerDevice{
+	{"/dev/null", 1, 3, 0666},
+	{"/dev/zero", 1, 5, 0666},
+	{"/dev/full", 1, 7, 0666},
+	{"/dev/random", 1, 8, 0666},
+	{"/dev/urandom", 1, 9, 0666},
+	{"/dev/tty", 5, 0, 0666},
+}
+
+var defaultContainerDevLinks = map[string]string{
+	"/dev/fd":     "/proc/self/fd",
+	"/dev/stdin":  "/proc/self/fd/0",
+	"/dev/stdout": "/proc/self/fd/1",
+	"/dev/stderr": "/proc/self/fd/2",
+	"/dev/ptmx":   "pts/ptmx",
+}
+
+// bindHostDevice is the fallback for when mknod is not permitted, such as
+// inside a user namespace, it bind mounts the host device node instead.
+func bindHostDevice(device containerDevice, target string) error {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return unix.Mount(device.path, target, "", unix.MS_BIND, "")
+}
+
+func createDevice(rootfs string, device containerDevice) error {
+	target := filepath.Join(rootfs, device.path)
+	err := unix.Mknod(target, unix.S_IFCHR|device.mode, int(unix.Mkdev(device.major, device.minor)))
+	if err == unix.EPERM {
+		return bindHostDevice(device, target)
+	}
+	if err != nil {
+		return err
+	}
+	// mknod honors the umask
+	return os.Chmod(target, os.FileMode(device.mode))
+}
+
+// createContainerDevices populates the /dev tmpfs of rootfs with the default
+// OCI device set and the standard /dev symlinks.
+func createContainerDevices(rootfs string) error {
+	for _, device := range defaultContainerDevices {
+		if err := createDevice(rootfs, device); err != nil {
+			return err
+		}
+	}
+	for link, target := range defaultContainerDevLinks {
+		if err := os.Symlink(target, filepath.Join(rootfs, link)); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const defaultContainerPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+func getEnvKey(kv string) string {
+	return strings.SplitN(kv, "=", 2)[0]
+}
+
+func getEnvValue(env []string, key string) (string, bool) {
+	for _, kv := range env {
+		if parts := strings.SplitN(kv, "=", 2); len(parts) == 2 && parts[0] == key {
+			return parts[1], true
+		}
+	}
+	return "", false
+}
+
+// mergeEnv merges every list of KEY=VAL entries over base, an entry in a
+// later list replaces the entry with the same key while keeping its position.
+func mergeEnv(base []string, overrides ...[]string) []string {
+	var env []string
+	index := map[string]int{}
+	for _, list := range append([][]string{base}, overrides...) {
+		for _, kv := range list {
+			key := getEnvKey(kv)
+			if i, ok := index[key]; ok {
+				env[i] = kv
+				continue
+			}
+			index[key] = len(env)
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// resolveEnvOption turns a -e or env file entry into KEY=VAL, a bare KEY takes
+// its value from the gocker process and is dropped when it is not set there.
+func resolveEnvOption(option string) (string, bool, error) {
+	if strings.HasPrefix(option, "=") || len(option) == 0 {
+		return "", false, fmt.Errorf("invalid environment variable: %q", option)
+	}
+	if strings.Contains(option, "=") {
+		return option, true, nil
+	}
+	if value, ok := os.LookupEnv(option); ok {
+		return option + "=" + value, true, nil
+	}
+	return "", false, nil
+}
+
+func parseEnvFile(envFile string) ([]string, error) {
+	var options []string
+	file, err := os.Open(envFile)
+	if err != nil {
+		return options, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		options = append(options, line)
+	}
+	return options, scanner.Err()
+}
+
+// parseEnvOptions resolves --env-file entries followed by -e entries, so
+// that -e wins over an env file.
+func parseEnvOptions(envs []string, envFiles []string) ([]string, error) {
+	var options []string
+	for _, envFile := range envFiles {
+		fileOptions, err := parseEnvFile(envFile)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, fileOptions...)
+	}
+	options = append(options, envs...)
+
+	var env []string
+	for _, option := range options {
+		kv, ok, err := resolveEnvOption(option)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			env = append(env, kv)
+		}
+	}
+	return mergeEnv(nil, env), nil
+}
+
+func buildContainerEnv(imgEnv []string, hostname string, userEnv []string) []string {
+	base := []string{"PATH=" + defaultContainerPath, "HOSTNAME=" + hostname}
+	return mergeEnv(base, imgEnv, userEnv)
+}
@@ -1,67 +1,196 @@
 package main
 
 import (
+	"encoding/json"
 	"golang.org/x/sys/unix"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"runtime"
 	"strconv"
-	"strings"
 )
 
-func getPidForRunningContainer(containerId string) int {
-	containers, err := getRunningContainers()
+// execSpecFd and execRootFd are the descriptors exec-mode reads the process to
+// execute from and finds the container root on, in cmd.ExtraFiles order.
+const (
+	execSpecFd = 3
+	execRootFd = 4
+)
+
+type execProcessSpec struct {
+	Path            string        `json:"path"`
+	Args            []string      `json:"args"`
+	Env             []string      `json:"env"`
+	User            containerUser `json:"user"`
+	WorkingDir      string        `json:"workingDir"`
+	Capabilities    []string      `json:"capabilities"`
+	SeccompProfile  string        `json:"seccompProfile,omitempty"`
+	NoNewPrivileges bool          `json:"noNewPrivileges"`
+	Ulimits         []ulimit      `json:"ulimits,omitempty"`
+	OOMScoreAdj     *int          `json:"oomScoreAdj,omitempty"`
+}
+
+// joinNamespace moves the calling thread into the namespace of pid of the
+// given type
+func joinNamespace(pid int, name string, nstype int) error {
+	fd, err := unix.Open("/proc/"+strconv.Itoa(pid)+"/ns/"+name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
 	if err != nil {
-		log.Fatalln("get running container failed")
+		return err
 	}
-	for _, container := range containers {
-		if container.containerId == containerId {
-			return container.pid
-		}
-	}
-	return 0
+	defer unix.Close(fd)
+	return unix.Setns(fd, nstype)
 }
 
-func execInContainer(containerId string) {
-	pid := getPidForRunningContainer(containerId)
-	if pid == 0 {
+func execInContainer(containerId string, args []string, userEnv []string, userFlag string, tty bool, interactive bool) int {
+	container, err := loadContainerState(containerId)
+	if err != nil {
 		log.Fatalln("no such container")
 	}
-	baseNsPath := "/proc/" + strconv.Itoa(pid) + "/exe"
-	ipcFd, ipcErr := os.Open(baseNsPath + "/ipc")
-	mntFd, mntErr := os.Open(baseNsPath + "/mnt")
-	netFd, netErr := os.Open(baseNsPath + "/net")
-	pidFd, pidErr := os.Open(baseNsPath + "/pid")
-	utsFd, utsErr := os.Open(baseNsPath + "/uts")
-
-	if ipcErr != nil || mntErr != nil || netErr != nil || pidErr != nil || utsErr != nil {
-		log.Fatalln("open namespace file failed")
+	if !isContainerRunning(container) {
+		log.Fatalf("container %s is not running\n", containerId)
 	}
+	if isRootless() && !isInUserNamespaceOf(container.Pid) {
+		return getExitCode(runInUserNamespaceOf(container.Pid, os.Args[1:]))
+	}
+	// setns only moves the calling thread, the command is forked from it
+	runtime.LockOSThread()
+	// a multi-threaded process can not join a mount namespace, the chroot into
+	// /proc/<pid>/root below is what provides the container file system
+	doOrDieWithMsg(joinNamespace(container.Pid, "ipc", unix.CLONE_NEWIPC), "join container IPC namespace failed")
+	doOrDieWithMsg(joinNamespace(container.Pid, "net", unix.CLONE_NEWNET), "join container network namespace failed")
+	doOrDieWithMsg(joinNamespace(container.Pid, "pid", unix.CLONE_NEWPID), "join container PID namespace failed")
+	doOrDieWithMsg(joinNamespace(container.Pid, "uts", unix.CLONE_NEWUTS), "join container UTS namespace failed")
 
-	unix.Setns(int(ipcFd.Fd()), unix.CLONE_NEWIPC)
-	unix.Setns(int(mntFd.Fd()), unix.CLONE_NEWNS)
-	unix.Setns(int(netFd.Fd()), unix.CLONE_NEWNET)
-	unix.Setns(int(pidFd.Fd()), unix.CLONE_NEWPID)
-	unix.Setns(int(utsFd.Fd()), unix.CLONE_NEWUTS)
-
-	container, err := getRunningContainerInfoForId(containerId)
-	if err != nil {
-		log.Fatalln("get running container info failed")
+	config, err := loadContainerConfig(containerId)
+	doOrDieWithMsg(err, "load container config failed")
+	if len(userFlag) == 0 {
+		userFlag = config.User
 	}
-	imgNameAndHash := strings.Split(container.image, ":")
-	exist, imageShaHex := imageExistByTag(imgNameAndHash[0], imgNameAndHash[1])
-	if !exist {
-		log.Fatalln("get image hash failed")
+	imgConfig := parseContainerConfig(container.ImageId)
+	// the root of the container init shows the mounts made in its mount namespace
+	containerRootPath := "/proc/" + strconv.Itoa(container.Pid) + "/root"
+	if canManageCGroups() {
+		createCGroups(containerId, os.Getpid(), false)
 	}
-	imgConfig := parseContainerConfig(imageShaHex)
-	containerMntPath := getGockerContainersPath() + "/" + containerId + "/fs/mnt"
-	createCGroups(containerId, false)
-	unix.Chroot(containerMntPath)
+	hostRoot, err := os.Open("/")
+	doOrDieWithMsg(err, "open host root failed")
+	defer hostRoot.Close()
+	containerRoot, err := os.Open(containerRootPath)
+	doOrDieWithMsg(err, "open container root failed")
+	defer containerRoot.Close()
+	doOrDieWithMsg(unix.Chroot(containerRootPath), "change root failed")
 	os.Chdir("/")
-	cmd := exec.Command(os.Args[3], os.Args[4:]...)
-	cmd.Stdin = os.Stdin
+	doOrDieWithMsg(changeToWorkingDir(imgConfig.Config.WorkingDir), "change to working dir failed")
+	workingDir, err := unix.Getwd()
+	doOrDieWithMsg(err, "get working dir failed")
+	user, err := resolveContainerUser(getContainerUserSpec(userFlag, imgConfig))
+	doOrDieWithMsg(err, "resolve user failed")
+	_, _, err = shiftOwner(config.UidMappings, config.GidMappings, user.Uid, user.Gid)
+	doOrDieWithMsg(err, "resolve user failed")
+	env := addUserHomeEnv(buildContainerEnv(imgConfig.Config.Env, config.Hostname, userEnv), user)
+	path, err := lookPathInContainer(args[0], env)
+	if err != nil {
+		log.Printf("find command failed: %v\n", err)
+		return exitCodeCommandNotFound
+	}
+	var master, slave *os.File
+	if tty {
+		// the chroot makes /dev/ptmx the container's devpts instance
+		master, slave, err = openPty()
+		doOrDieWithMsg(err, "allocate pty failed")
+		// a rootless exec already runs in the container user namespace
+		ptyOwner := user.Uid
+		if !isRootless() {
+			ptyOwner, err = shiftId(config.UidMappings, user.Uid)
+			doOrDieWithMsg(err, "change pty owner failed")
+		}
+		doOrDieWithMsg(slave.Chown(ptyOwner, -1), "change pty owner failed")
+	}
+	// exec-mode is started from the host root, so it does not depend on the
+	// libraries of the image, and enters the container root by itself
+	doOrDieWithMsg(unix.Fchdir(int(hostRoot.Fd())), "change to host root failed")
+	doOrDieWithMsg(unix.Chroot("."), "change root failed")
+	specReader, specWriter, err := os.Pipe()
+	doOrDieWithMsg(err, "create exec spec pipe failed")
+	defer specWriter.Close()
+	cmd := exec.Command("/proc/self/exe", "exec-mode")
+	if interactive {
+		cmd.Stdin = os.Stdin
+	}
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = imgConfig.Config.Env
-	cmd.Run()
+	cmd.ExtraFiles = []*os.File{specReader, containerRoot}
+	cmd.SysProcAttr = &unix.SysProcAttr{}
+	if len(config.UidMappings) > 0 && !isRootless() {
+		// a multi-threaded process can not join the container's user
+		// namespace, the command gets its own one with the same mappings
+		setUserNamespaceAttr(cmd.SysProcAttr, config.UidMappings, config.GidMappings)
+	}
+	if tty {
+		cmd.Stdin = slave
+		cmd.Stdout = slave
+		cmd.Stderr = slave
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+		cmd.SysProcAttr.Ctty = 0
+	}
+	err = cmd.Start()
+	specReader.Close()
+	if err != nil {
+		log.Printf("exec command failed: %v\n", err)
+		return exitCodeCommandNotRunnable
+	}
+	spec := execProcessSpec{
+		Path:            path,
+		Args:            args,
+		Env:             env,
+		User:            user,
+		WorkingDir:      workingDir,
+		Capabilities:    config.Capabilities,
+		SeccompProfile:  config.SeccompProfile,
+		NoNewPrivileges: config.NoNewPrivileges,
+		Ulimits:         config.Ulimits,
+		OOMScoreAdj:     config.OOMScoreAdj,
+	}
+	if err := json.NewEncoder(specWriter).Encode(spec); err != nil {
+		log.Printf("send exec spec failed: %v\n", err)
+	}
+	specWriter.Close()
+	if master == nil {
+		return getExitCode(cmd.Wait())
+	}
+	slave.Close()
+	outputDone := make(chan struct{})
+	go func() {
+		io.Copy(os.Stdout, master)
+		close(outputDone)
+	}()
+	restoreTerminal := attachTerminal(master, interactive)
+	err = cmd.Wait()
+	<-outputDone
+	restoreTerminal()
+	return getExitCode(err)
+}
+
+// execContainerProcess is exec-mode, it enters the container root, drops to
+// the container user, capabilities and seccomp profile and executes the
+// process execInContainer resolved.
+func execContainerProcess() {
+	// capabilities and the seccomp filter are set per thread
+	runtime.LockOSThread()
+	specPipe := os.NewFile(execSpecFd, "exec-spec")
+	data, err := io.ReadAll(specPipe)
+	specPipe.Close()
+	doOrDieWithMsg(err, "read exec spec failed")
+	spec := execProcessSpec{}
+	doOrDieWithMsg(json.Unmarshal(data, &spec), "parse exec spec failed")
+	doOrDieWithMsg(unix.Fchdir(execRootFd), "change to container root failed")
+	unix.Close(execRootFd)
+	doOrDieWithMsg(unix.Chroot("."), "change root failed")
+	doOrDieWithMsg(os.Chdir(spec.WorkingDir), "change to working dir failed")
+	doOrDieWithMsg(applyProcessSecurity(spec.User, spec.Capabilities, spec.SeccompProfile, spec.NoNewPrivileges, spec.Ulimits, spec.OOMScoreAdj), "drop container privileges failed")
+	err = unix.Exec(spec.Path, spec.Args, spec.Env)
+	log.Printf("exec command failed: %v\n", err)
+	os.Exit(exitCodeCommandNotRunnable)
 }
@@ -17,8 +17,15 @@ type manifest []struct {
 }
 
 type imageConfigDetails struct {
-	Env []string `json:"Env"`
-	Cmd []string `json:"Cmd"`
+	User         string              `json:"User"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Env          []string            `json:"Env"`
+	Entrypoint   []string            `json:"Entrypoint"`
+	Cmd          []string            `json:"Cmd"`
+	Volumes      map[string]struct{} `json:"Volumes"`
+	WorkingDir   string              `json:"WorkingDir"`
+	Labels       map[string]string   `json:"Labels"`
+	StopSignal   string              `json:"StopSignal"`
 }
 
 type imageConfig struct {
@@ -42,7 +49,7 @@ func downloadImageIfRequired(src string) string {
 
 		log.Println("checking if image exists under another name")
 		altImgName, altImgtag := imageExistByHash(imageShaHex)
-		if len(altImgtag) > 0 && len(altImgtag) > 0 {
+		if len(altImgName) > 0 && len(altImgtag) > 0 {
 			log.Printf("the image you want is exist as %s:%s\n", altImgName, altImgtag)
 			storeImageMetadata(imgName, tagName, imageShaHex)
 			return imageShaHex
@@ -109,19 +116,39 @@ func parseContainerConfig(imageShaHex string) imageConfig {
 	return imgConfig
 }
 
+// resolveContainerCommand combines the image Entrypoint and Cmd with the
+// command line the way docker does: an --entrypoint override also drops the
+// image Cmd, and arguments given on the command line replace the image Cmd.
+func resolveContainerCommand(imgConfig imageConfig, entrypoint *string, args []string) []string {
+	var command []string
+	if entrypoint != nil {
+		if len(*entrypoint) > 0 {
+			command = append(command, *entrypoint)
+		}
+	} else {
+		command = append(command, imgConfig.Config.Entrypoint...)
+	}
+	if len(args) > 0 {
+		command = append(command, args...)
+	} else if entrypoint == nil {
+		command = append(command, imgConfig.Config.Cmd...)
+	}
+	return command
+}
+
 func deleteImageByHash(imageShaHex string) {
-	imgName, tagName := imageExistByHash(imageShaHex)
+	imgName, _ := imageExistByHash(imageShaHex)
 	if len(imgName) == 0 {
 		log.Println("no such image")
 		return
 	}
-	containers, err := getRunningContainers()
+	containers, err := getContainerStates()
 	if err != nil {
-		log.Fatalln("get running container failed")
+		log.Fatalln("get containers failed")
 	}
 	for _, container := range containers {
-		if container.image == imgName+":"+tagName {
-			log.Fatalf("cat not delete the image used by container %s", container.containerId)
+		if container.ImageId == imageShaHex {
+			log.Fatalf("cat not delete the image used by container %s", container.Id)
 		}
 	}
 	if err := os.RemoveAll(getGockerImagesPath() + "/" + imageShaHex); err != nil {
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"debug/elf"
+	"encoding/json"
+	"golang.org/x/sys/unix"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// relayedSignals are forwarded from the supervisor to the container init
+var relayedSignals = []os.Signal{unix.SIGINT, unix.SIGTERM, unix.SIGHUP, unix.SIGUSR1, unix.SIGUSR2}
+
+// relaySignals forwards relayedSignals received by this process to pid until
+// the returned function is called.
+func relaySignals(pid int) func() {
+	sigCh := make(chan os.Signal, 8)
+	signal.Notify(sigCh, relayedSignals...)
+	go func() {
+		for sig := range sigCh {
+			signalContainerProcess(pid, sig.(syscall.Signal))
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+	}
+}
+
+// isInitForwardedSignal tells which signals the container init passes on to
+// its child, SIGCHLD is used for reaping and the rest are job control or
+// runtime noise.
+func isInitForwardedSignal(sig syscall.Signal) bool {
+	switch sig {
+	case unix.SIGCHLD, unix.SIGURG, unix.SIGPIPE, unix.SIGTTIN, unix.SIGTTOU:
+		return false
+	}
+	return true
+}
+
+// reapChildren waits for every exited child and reports whether pid was one
+// of them along with its wait status.
+func reapChildren(pid int) (bool, syscall.WaitStatus) {
+	var exited bool
+	var exitStatus syscall.WaitStatus
+	for {
+		var status syscall.WaitStatus
+		reaped, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || reaped <= 0 {
+			return exited, exitStatus
+		}
+		if reaped == pid {
+			exited, exitStatus = true, status
+		}
+	}
+}
+
+// initSpec is the command the container init runs, child-mode hands it over
+// in a memfd since the init is executed without the container environment.
+type initSpec struct {
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+}
+
+// initExecutable keeps the gocker binary, its ELF interpreter and the
+// directories of its libraries open, so that it can be executed again as the
+// container init once the host root is gone. A statically linked gocker has
+// no interpreter.
+type initExecutable struct {
+	exe     int
+	interp  int
+	libDirs []int
+}
+
+func getFdPath(fd int) string {
+	return "/proc/self/fd/" + strconv.Itoa(fd)
+}
+
+// getLibraryDirs returns the directories of the files mapped into this
+// process other than its executable, that is its shared libraries.
+func getLibraryDirs() ([]string, error) {
+	exe, err := os.Readlink("/proc/self/exe")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile("/proc/self/maps")
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 6 || !strings.HasPrefix(fields[5], "/") || fields[5] == exe {
+			continue
+		}
+		if dir := filepath.Dir(fields[5]); !stringInSlice(dir, dirs) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// openInitExecutable runs before changing into the container root, which
+// may not hold the interpreter and libraries gocker is linked against. Only
+// the interpreter is opened close-on-exec, the init closes the others.
+func openInitExecutable() (*initExecutable, error) {
+	binary, err := elf.Open("/proc/self/exe")
+	if err != nil {
+		return nil, err
+	}
+	defer binary.Close()
+	var interp string
+	for _, prog := range binary.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data, err := io.ReadAll(prog.Open())
+		if err != nil {
+			return nil, err
+		}
+		interp = strings.TrimRight(string(data), "\x00")
+	}
+	exe := &initExecutable{interp: -1}
+	if exe.exe, err = unix.Open("/proc/self/exe", unix.O_PATH, 0); err != nil {
+		return nil, err
+	}
+	if len(interp) == 0 {
+		return exe, nil
+	}
+	if exe.interp, err = unix.Open(interp, unix.O_PATH|unix.O_CLOEXEC, 0); err != nil {
+		return nil, err
+	}
+	dirs, err := getLibraryDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		fd, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY, 0)
+		if err != nil {
+			return nil, err
+		}
+		exe.libDirs = append(exe.libDirs, fd)
+	}
+	return exe, nil
+}
+
+// exec replaces child-mode with the container init once it has dropped to
+// the container user, capabilities and seccomp profile. Unlike child-mode,
+// whose other threads keep their privileges, every thread of the init starts
+// out with the dropped ones. A dynamically linked gocker is loaded through
+// its interpreter, which is given the library directories to search.
+func (e *initExecutable) exec(spec initSpec) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	specFd, err := unix.MemfdCreate("init-spec", 0)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		n, err := unix.Write(specFd, data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	if _, err := unix.Seek(specFd, 0, io.SeekStart); err != nil {
+		return err
+	}
+	args := []string{"init", strconv.Itoa(specFd), strconv.Itoa(e.exe)}
+	for _, fd := range e.libDirs {
+		args = append(args, strconv.Itoa(fd))
+	}
+	if e.interp < 0 {
+		return unix.Exec(getFdPath(e.exe), append([]string{"gocker"}, args...), nil)
+	}
+	var libPath []string
+	for _, fd := range e.libDirs {
+		libPath = append(libPath, getFdPath(fd))
+	}
+	loaderArgs := []string{"ld.so", "--library-path", strings.Join(libPath, ":"), getFdPath(e.exe)}
+	return unix.Exec(getFdPath(e.interp), append(loaderArgs, args...), nil)
+}
+
+// execContainerInit is the container init executed by child-mode, args are
+// the memfd holding its spec followed by the other descriptors it inherited.
+// Those refer to the host file system and are closed before the command is
+// started, as the container could reach them through /proc/1/fd.
+func execContainerInit(args []string) int {
+	if len(args) == 0 {
+		log.Println("init: spec is needed")
+		return exitCodeCommandNotRunnable
+	}
+	var fds []int
+	for _, arg := range args {
+		fd, err := strconv.Atoi(arg)
+		if err != nil || fd <= 2 {
+			log.Printf("init: invalid descriptor %s\n", arg)
+			return exitCodeCommandNotRunnable
+		}
+		fds = append(fds, fd)
+	}
+	// the init is executed by descriptor and would be named after its number
+	os.WriteFile("/proc/self/comm", []byte("gocker-init"), 0)
+	specFile := os.NewFile(uintptr(fds[0]), "init-spec")
+	data, err := io.ReadAll(specFile)
+	specFile.Close()
+	for _, fd := range fds[1:] {
+		unix.Close(fd)
+	}
+	if err != nil {
+		log.Printf("init: read spec failed: %v\n", err)
+		return exitCodeCommandNotRunnable
+	}
+	spec := initSpec{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		log.Printf("init: parse spec failed: %v\n", err)
+		return exitCodeCommandNotRunnable
+	}
+	return runContainerInit(spec.Path, spec.Args, spec.Env)
+}
+
+// runContainerInit runs the command, forwards signals to it, reaps orphaned
+// processes and returns the command's exit code.
+func runContainerInit(path string, args []string, env []string) int {
+	sigCh := make(chan os.Signal, 32)
+	signal.Notify(sigCh)
+	cmd := &exec.Cmd{
+		Path:   path,
+		Args:   args,
+		Env:    env,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	cmd.SysProcAttr = &unix.SysProcAttr{Setpgid: true}
+	if isTerminal(0) {
+		// hand the terminal to the command so job control signals reach it
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = 0
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("exec container command failed: %v\n", err)
+		return exitCodeCommandNotRunnable
+	}
+	pid := cmd.Process.Pid
+	for sig := range sigCh {
+		sysSig := sig.(syscall.Signal)
+		if sysSig != unix.SIGCHLD {
+			if isInitForwardedSignal(sysSig) {
+				unix.Kill(pid, sysSig)
+			}
+			continue
+		}
+		if exited, status := reapChildren(pid); exited {
+			return getWaitStatusExitCode(status)
+		}
+	}
+	return 0
+}
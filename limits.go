@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const unlimitedUlimit = "unlimited"
+
+var ulimitResources = map[string]int{
+	"as":         unix.RLIMIT_AS,
+	"core":       unix.RLIMIT_CORE,
+	"cpu":        unix.RLIMIT_CPU,
+	"data":       unix.RLIMIT_DATA,
+	"fsize":      unix.RLIMIT_FSIZE,
+	"locks":      unix.RLIMIT_LOCKS,
+	"memlock":    unix.RLIMIT_MEMLOCK,
+	"msgqueue":   unix.RLIMIT_MSGQUEUE,
+	"nice":       unix.RLIMIT_NICE,
+	"nofile":     unix.RLIMIT_NOFILE,
+	"nproc":      unix.RLIMIT_NPROC,
+	"rss":        unix.RLIMIT_RSS,
+	"rtprio":     unix.RLIMIT_RTPRIO,
+	"rttime":     unix.RLIMIT_RTTIME,
+	"sigpending": unix.RLIMIT_SIGPENDING,
+	"stack":      unix.RLIMIT_STACK,
+}
+
+type ulimit struct {
+	Name string `json:"name"`
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+}
+
+func parseUlimitValue(value string) (uint64, error) {
+	if value == unlimitedUlimit || value == "-1" {
+		return unix.RLIM_INFINITY, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// parseUlimit parses a --ulimit value of the form <name>=<soft>[:<hard>], the
+// hard limit defaults to the soft one
+func parseUlimit(spec string) (ulimit, error) {
+	name, values, ok := strings.Cut(spec, "=")
+	if !ok {
+		return ulimit{}, fmt.Errorf("invalid ulimit: %s", spec)
+	}
+	if _, ok := ulimitResources[name]; !ok {
+		return ulimit{}, fmt.Errorf("unknown ulimit: %s", name)
+	}
+	softValue, hardValue, ok := strings.Cut(values, ":")
+	if !ok {
+		hardValue = softValue
+	}
+	soft, err := parseUlimitValue(softValue)
+	if err != nil {
+		return ulimit{}, fmt.Errorf("invalid ulimit: %s", spec)
+	}
+	hard, err := parseUlimitValue(hardValue)
+	if err != nil {
+		return ulimit{}, fmt.Errorf("invalid ulimit: %s", spec)
+	}
+	if soft > hard {
+		return ulimit{}, fmt.Errorf("soft limit is above the hard limit: %s", spec)
+	}
+	return ulimit{Name: name, Soft: soft, Hard: hard}, nil
+}
+
+func parseUlimits(specs []string) ([]ulimit, error) {
+	var ulimits []ulimit
+	for _, spec := range specs {
+		limit, err := parseUlimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		ulimits = append(ulimits, limit)
+	}
+	return ulimits, nil
+}
+
+// applyUlimits sets the resource limits of the calling process, raising a
+// hard limit needs CAP_SYS_RESOURCE so it runs before dropping capabilities.
+// syscall.Setrlimit is used since it keeps the Go runtime from restoring its
+// own RLIMIT_NOFILE on exec.
+func applyUlimits(ulimits []ulimit) error {
+	for _, limit := range ulimits {
+		rlimit := syscall.Rlimit{Cur: limit.Soft, Max: limit.Hard}
+		if err := syscall.Setrlimit(ulimitResources[limit.Name], &rlimit); err != nil {
+			return fmt.Errorf("set %s limit failed: %v", limit.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateOOMScoreAdj(score int) error {
+	if score < -1000 || score > 1000 {
+		return fmt.Errorf("invalid oom score adjustment %d, it must be between -1000 and 1000", score)
+	}
+	return nil
+}
+
+// writeOOMScoreAdj adjusts the OOM score of the calling process, which its
+// children inherit. Lowering it needs CAP_SYS_RESOURCE.
+func writeOOMScoreAdj(score int) error {
+	return os.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(score)), 0644)
+}
+
+// applyProcessLimits applies the ulimits and OOM score adjustment of a
+// container to the calling process, before it drops its capabilities.
+func applyProcessLimits(ulimits []ulimit, oomScoreAdj *int) error {
+	if err := applyUlimits(ulimits); err != nil {
+		return err
+	}
+	if oomScoreAdj == nil {
+		return nil
+	}
+	if err := writeOOMScoreAdj(*oomScoreAdj); err != nil {
+		return fmt.Errorf("write oom score adjustment failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	logStreamStdout = "stdout"
+	logStreamStderr = "stderr"
+)
+
+type logEntry struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+type containerLogger struct {
+	mu   sync.Mutex
+	file *os.File
+	wg   sync.WaitGroup
+}
+
+func getContainerLogPath(containerId string) string {
+	return getContainerHome(containerId) + "/container.log"
+}
+
+func newContainerLogger(containerId string) (*containerLogger, error) {
+	file, err := os.OpenFile(getContainerLogPath(containerId), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &containerLogger{file: file}, nil
+}
+
+func (l *containerLogger) writeEntry(stream string, line string) {
+	data, err := json.Marshal(logEntry{Log: line, Stream: stream, Time: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		log.Printf("write container log failed: %v\n", err)
+	}
+}
+
+// copyStream logs src line by line under the given stream name until EOF.
+// What is read is teed to tee as it arrives when tee is not nil, only the log
+// entries wait for a line to be complete.
+func (l *containerLogger) copyStream(stream string, src io.ReadCloser, tee io.Writer) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer src.Close()
+		var line []byte
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				if tee != nil {
+					tee.Write(buf[:n])
+				}
+				line = append(line, buf[:n]...)
+				for {
+					end := bytes.IndexByte(line, '\n')
+					if end < 0 {
+						break
+					}
+					l.writeEntry(stream, string(line[:end+1]))
+					line = line[end+1:]
+				}
+			}
+			if err != nil {
+				if len(line) > 0 {
+					l.writeEntry(stream, string(line))
+				}
+				return
+			}
+		}
+	}()
+}
+
+func (l *containerLogger) close() {
+	l.wg.Wait()
+	l.file.Close()
+}
+
+func parseLogsSince(since string) (time.Time, error) {
+	if len(since) == 0 {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(since, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value: %s", since)
+}
+
+// readLogEntries returns the complete entries in reader along with the number
+// of bytes they took, a partially written last line is left for the next read.
+func readLogEntries(reader io.Reader, since time.Time) ([]logEntry, int64, error) {
+	var entries []logEntry
+	var consumed int64
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, consumed, nil
+		}
+		if err != nil {
+			return entries, consumed, err
+		}
+		consumed += int64(len(line))
+		entry := logEntry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		if entry.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func printLogEntries(entries []logEntry) {
+	for _, entry := range entries {
+		if entry.Stream == logStreamStderr {
+			fmt.Fprint(os.Stderr, entry.Log)
+		} else {
+			fmt.Fprint(os.Stdout, entry.Log)
+		}
+	}
+}
+
+func isContainerActive(containerId string) bool {
+	state, err := loadContainerState(containerId)
+	return err == nil && (isContainerSupervised(state) || isContainerRunning(state))
+}
+
+func printContainerLogs(containerId string, follow bool, since string, tail int) {
+	if _, err := loadContainerState(containerId); err != nil {
+		log.Fatalln("no such container")
+	}
+	sinceTime, err := parseLogsSince(since)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	file, err := os.Open(getContainerLogPath(containerId))
+	for follow && os.IsNotExist(err) && isContainerActive(containerId) {
+		time.Sleep(200 * time.Millisecond)
+		file, err = os.Open(getContainerLogPath(containerId))
+	}
+	if os.IsNotExist(err) {
+		return
+	}
+	doOrDieWithMsg(err, "open container log failed")
+	defer file.Close()
+
+	entries, offset, err := readLogEntries(file, sinceTime)
+	doOrDieWithMsg(err, "read container log failed")
+	if tail >= 0 && len(entries) > tail {
+		entries = entries[len(entries)-tail:]
+	}
+	printLogEntries(entries)
+	if !follow {
+		return
+	}
+
+	for {
+		running := isContainerActive(containerId)
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			log.Fatalf("seek container log failed: %v\n", err)
+		}
+		entries, consumed, err := readLogEntries(file, sinceTime)
+		doOrDieWithMsg(err, "read container log failed")
+		offset += consumed
+		printLogEntries(entries)
+		if !running {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
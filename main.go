@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	flag "github.com/spf13/pflag"
 	"log"
 	"math/rand"
@@ -11,15 +12,26 @@ import (
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
-	options := []string{"run", "child-mode", "setup-netns", "setup-veth", "ps", "exec", "images", "rmi"}
+	options := []string{"run", "child-mode", "exec-mode", "init", "rootless-rm", "signal-process", "shim", "setup-netns", "setup-veth", "ps", "exec", "images", "rmi", "stop", "kill", "start", "rm", "logs", "wait", "inspect", "volume"}
 
 	if len(os.Args) < 2 || !stringInSlice(os.Args[1], options) {
 		usage()
 		os.Exit(1)
 	}
 
-	if os.Getuid() != 0 {
-		log.Fatalf("you need root privilege to run this program")
+	// exec-mode and init run inside the container and must not touch the host
+	// directories
+	if os.Args[1] == "exec-mode" {
+		execContainerProcess()
+	}
+	if os.Args[1] == "init" {
+		os.Exit(execContainerInit(os.Args[2:]))
+	}
+
+	if isRootless() {
+		// the helpers executed in a user namespace see themselves as root
+		os.Setenv(rootlessEnv, "1")
+		useRootlessPaths()
 	}
 
 	if err := initGockerDirs(); err != nil {
@@ -35,43 +47,188 @@ func main() {
 		swap := fs.Int("swap", -1, "Max swap to allow in MB")
 		pids := fs.Int("pids", -1, "Max number of processes to allow")
 		cpus := fs.Float64("cpus", -1, "Number of cpu core to restrict to ")
+		detach := fs.BoolP("detach", "d", false, "Run container in background and print container ID")
+		tty := fs.BoolP("tty", "t", false, "Allocate a pseudo-TTY")
+		interactive := fs.BoolP("interactive", "i", false, "Keep STDIN open")
+		autoRemove := fs.Bool("rm", false, "Remove the container when it exits")
+		useInit := fs.Bool("init", false, "Run an init inside the container that forwards signals and reaps processes")
+		entrypoint := fs.String("entrypoint", "", "Overwrite the default entrypoint of the image")
+		envs := fs.StringArrayP("env", "e", nil, "Set environment variables")
+		envFiles := fs.StringArray("env-file", nil, "Read in a file of environment variables")
+		user := fs.StringP("user", "u", "", "Username or UID (format: <name|uid>[:<group|gid>])")
+		name := fs.String("name", "", "Assign a name to the container")
+		hostname := fs.StringP("hostname", "h", "", "Container host name")
+		volumes := fs.StringArrayP("volume", "v", nil, "Bind mount a volume (format: <host-path>:<container-path>[:<options>])")
+		mountSpecs := fs.StringArray("mount", nil, "Attach a filesystem mount to the container")
+		tmpfs := fs.StringArray("tmpfs", nil, "Mount a tmpfs directory (format: <path>[:<options>])")
+		shmSize := fs.String("shm-size", "64m", "Size of /dev/shm")
+		readOnly := fs.Bool("read-only", false, "Mount the container's root filesystem as read only")
+		rootfsPropagation := fs.String("rootfs-propagation", defaultRootfsPropagation, "Mount propagation of the container root (private, rprivate, slave, rslave, shared, rshared)")
+		capAdd := fs.StringArray("cap-add", nil, "Add Linux capabilities")
+		capDrop := fs.StringArray("cap-drop", nil, "Drop Linux capabilities")
+		privileged := fs.Bool("privileged", false, "Give extended privileges to this container")
+		securityOpts := fs.StringArray("security-opt", nil, "Security options (seccomp=<profile.json|unconfined>, unmask=<ALL|paths>, no-new-privileges=<true|false>)")
+		ulimitSpecs := fs.StringArray("ulimit", nil, "Ulimit options (format: <name>=<soft>[:<hard>])")
+		sysctlOpts := fs.StringArray("sysctl", nil, "Namespaced kernel parameters (format: <key>=<value>)")
+		oomScoreAdj := fs.Int("oom-score-adj", 0, "Tune the host's OOM preferences for the container (-1000 to 1000)")
+		usernsRemap := fs.String("userns-remap", "", "Run in a user namespace with the subordinate ids of a user (default: gocker)")
+		uidMaps := fs.StringArray("uidmap", nil, "UID map for the user namespace (format: <container-uid>:<host-uid>:<size>)")
+		gidMaps := fs.StringArray("gidmap", nil, "GID map for the user namespace (format: <container-gid>:<host-gid>:<size>)")
+		fs.SetInterspersed(false)
 		if err := fs.Parse(os.Args[2:]); err != nil {
 			log.Fatalf("parse arguments failed: %v\n", err)
 		}
-		if len(fs.Args()) < 2 {
-			log.Fatalln("image name and commands are needed")
+		if len(fs.Args()) < 1 {
+			log.Fatalln("image name is needed")
 		}
-
-		if isUp, _ := isGockerBridgeUp(); !isUp {
-			if err := setupGockerBridge(); err != nil {
-				log.Fatalf("set up gocker bridge failed: %v\n", err)
+		if !fs.Changed("entrypoint") {
+			entrypoint = nil
+		}
+		env, err := parseEnvOptions(*envs, *envFiles)
+		if err != nil {
+			log.Fatalf("parse environment variables failed: %v\n", err)
+		}
+		mounts, err := parseMountOptions(*volumes, *mountSpecs, *tmpfs)
+		if err != nil {
+			log.Fatalf("parse mounts failed: %v\n", err)
+		}
+		shmBytes, err := parseByteSize(*shmSize)
+		if err != nil {
+			log.Fatalf("parse shm size failed: %v\n", err)
+		}
+		if err := validateRootfsPropagation(*rootfsPropagation); err != nil {
+			log.Fatalln(err)
+		}
+		capabilities, err := resolveCapabilities(*capAdd, *capDrop, *privileged)
+		if err != nil {
+			log.Fatalf("parse capabilities failed: %v\n", err)
+		}
+		security, err := parseSecurityOptions(*securityOpts)
+		if err != nil {
+			log.Fatalf("parse security options failed: %v\n", err)
+		}
+		if *privileged && len(security.SeccompProfile) == 0 {
+			// like docker, privileged containers are not filtered unless asked to
+			security.SeccompProfile = seccompUnconfined
+		}
+		if seccompNativeArch == 0 && len(security.SeccompProfile) == 0 {
+			log.Println("seccomp is not supported on this architecture, the container runs unconfined")
+			security.SeccompProfile = seccompUnconfined
+		}
+		if _, err := getSeccompFilter(security.SeccompProfile, capabilities); err != nil {
+			log.Fatalf("compile seccomp profile failed: %v\n", err)
+		}
+		ulimits, err := parseUlimits(*ulimitSpecs)
+		if err != nil {
+			log.Fatalf("parse ulimits failed: %v\n", err)
+		}
+		sysctls, err := parseSysctlOptions(*sysctlOpts)
+		if err != nil {
+			log.Fatalf("parse sysctls failed: %v\n", err)
+		}
+		if !fs.Changed("oom-score-adj") {
+			oomScoreAdj = nil
+		} else if err := validateOOMScoreAdj(*oomScoreAdj); err != nil {
+			log.Fatalln(err)
+		}
+		maskedPaths, readonlyPaths := getContainerPathMasks(security.Unmask, *privileged)
+		uidMappings, gidMappings, err := getUserNamespaceMappings(*usernsRemap, *uidMaps, *gidMaps)
+		if err != nil {
+			log.Fatalf("parse user namespace mappings failed: %v\n", err)
+		}
+		if isRootless() {
+			if len(uidMappings) > 0 {
+				log.Fatalln("--userns-remap, --uidmap and --gidmap are not supported in rootless mode")
 			}
+			uidMappings, gidMappings = getRootlessMappings()
+			if (*mem > 0 || *swap > 0 || *pids > 0 || *cpus > 0) && !canManageCGroups() {
+				log.Println("no cgroups delegated to the user, ignoring resource limits")
+			}
+		} else {
+			ensureGockerBridge()
 		}
-
-		initContainer(*mem, *swap, *pids, *cpus, fs.Args()[0], fs.Args()[1:])
+		os.Exit(initContainer(containerConfig{
+			Image:             fs.Args()[0],
+			Args:              fs.Args()[1:],
+			Entrypoint:        entrypoint,
+			Env:               env,
+			User:              *user,
+			Name:              *name,
+			Hostname:          *hostname,
+			Mounts:            mounts,
+			ShmSize:           shmBytes,
+			ReadOnly:          *readOnly,
+			RootfsPropagation: *rootfsPropagation,
+			Capabilities:      capabilities,
+			Privileged:        *privileged,
+			SeccompProfile:    security.SeccompProfile,
+			MaskedPaths:       maskedPaths,
+			ReadonlyPaths:     readonlyPaths,
+			NoNewPrivileges:   security.NoNewPrivileges,
+			Ulimits:           ulimits,
+			OOMScoreAdj:       oomScoreAdj,
+			Sysctls:           sysctls,
+			UidMappings:       uidMappings,
+			GidMappings:       gidMappings,
+			Mem:               *mem,
+			Swap:              *swap,
+			Pids:              *pids,
+			Cpus:              *cpus,
+			Tty:               *tty,
+			Interactive:       *interactive,
+			Init:              *useInit,
+			Detach:            *detach,
+			AutoRemove:        *autoRemove,
+		}))
 	case "child-mode":
-		fs := flag.FlagSet{}
-		fs.ParseErrorsWhitelist.UnknownFlags = true
-
-		mem := fs.Int("mem", -1, "Max RAM to allow in MB")
-		swap := fs.Int("swap", -1, "Max swap to allow in MB")
-		pids := fs.Int("pids", -1, "Max number of processes to allow")
-		cpus := fs.Float64("cpus", -1, "Number of cpu core to restrict to ")
-		image := fs.String("img", "", "container image")
-		if err := fs.Parse(os.Args[2:]); err != nil {
-			log.Fatalln("parse arguments failed")
+		if len(os.Args) < 3 {
+			log.Fatalln("container id is needed")
 		}
-		if len(fs.Args()) < 2 {
-			log.Fatalln("image and command are needed")
+		execContainerCommand(os.Args[2])
+	case "shim":
+		if len(os.Args) < 3 {
+			log.Fatalln("container id is needed")
 		}
-		execContainerCommand(*mem, *swap, *pids, *cpus, fs.Args()[0], *image, fs.Args()[1:])
+		runContainerShim(os.Args[2])
+	case "rootless-rm":
+		runRootlessRemove(os.Args[2:])
+	case "signal-process":
+		runSignalProcess(os.Args[2:])
 	case "setup-netns":
 		setupNewNetworkNamespace(os.Args[2])
 	case "setup-veth":
 		setupContainerNetworkInterfaceStep1(os.Args[2])
 		setupContainerNetworkInterfaceStep2(os.Args[2])
 	case "ps":
-		printRunningContainers()
+		fs := flag.FlagSet{}
+		all := fs.BoolP("all", "a", false, "Show all containers")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		printContainers(*all)
+	case "start":
+		fs := flag.FlagSet{}
+		attach := fs.BoolP("attach", "a", false, "Run container in foreground")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		if len(fs.Args()) < 1 {
+			log.Fatalln("container id is needed")
+		}
+		if !isRootless() {
+			ensureGockerBridge()
+		}
+		os.Exit(startContainer(resolveContainerIdOrDie(fs.Args()[0]), *attach))
+	case "rm":
+		fs := flag.FlagSet{}
+		force := fs.BoolP("force", "f", false, "Kill the container if it is running")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		if len(fs.Args()) < 1 {
+			log.Fatalln("container id is needed")
+		}
+		removeContainer(resolveContainerIdOrDie(fs.Args()[0]), *force)
 	case "rmi":
 		if len(os.Args) < 3 {
 			usage()
@@ -79,7 +236,68 @@ func main() {
 		}
 		deleteImageByHash(os.Args[2])
 	case "exec":
-		execInContainer(os.Args[2])
+		fs := flag.FlagSet{}
+		envs := fs.StringArrayP("env", "e", nil, "Set environment variables")
+		envFiles := fs.StringArray("env-file", nil, "Read in a file of environment variables")
+		user := fs.StringP("user", "u", "", "Username or UID (format: <name|uid>[:<group|gid>])")
+		tty := fs.BoolP("tty", "t", false, "Allocate a pseudo-TTY")
+		interactive := fs.BoolP("interactive", "i", false, "Keep STDIN open")
+		fs.SetInterspersed(false)
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		if len(fs.Args()) < 2 {
+			log.Fatalln("container id and commands are needed")
+		}
+		env, err := parseEnvOptions(*envs, *envFiles)
+		if err != nil {
+			log.Fatalf("parse environment variables failed: %v\n", err)
+		}
+		os.Exit(execInContainer(resolveContainerIdOrDie(fs.Args()[0]), fs.Args()[1:], env, *user, *tty, *interactive))
+	case "stop":
+		fs := flag.FlagSet{}
+		timeout := fs.IntP("time", "t", 10, "Seconds to wait for the container to stop before killing it")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		if len(fs.Args()) < 1 {
+			log.Fatalln("container id is needed")
+		}
+		stopContainer(resolveContainerIdOrDie(fs.Args()[0]), *timeout)
+	case "kill":
+		fs := flag.FlagSet{}
+		signal := fs.StringP("signal", "s", "KILL", "Signal to send to the container")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		if len(fs.Args()) < 1 {
+			log.Fatalln("container id is needed")
+		}
+		killContainer(resolveContainerIdOrDie(fs.Args()[0]), *signal)
+	case "wait":
+		if len(os.Args) < 3 {
+			log.Fatalln("container id is needed")
+		}
+		fmt.Println(waitContainer(resolveContainerIdOrDie(os.Args[2])))
+	case "logs":
+		fs := flag.FlagSet{}
+		follow := fs.BoolP("follow", "f", false, "Follow log output")
+		since := fs.String("since", "", "Show logs since timestamp or relative duration like 10m")
+		tail := fs.Int("tail", -1, "Number of lines to show from the end of the logs")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("parse arguments failed: %v\n", err)
+		}
+		if len(fs.Args()) < 1 {
+			log.Fatalln("container id is needed")
+		}
+		printContainerLogs(resolveContainerIdOrDie(fs.Args()[0]), *follow, *since, *tail)
+	case "inspect":
+		if len(os.Args) < 3 {
+			log.Fatalln("container id is needed")
+		}
+		inspectContainer(resolveContainerIdOrDie(os.Args[2]))
+	case "volume":
+		runVolumeCommand(os.Args[2:])
 	case "images":
 		printAvailableImages()
 	default:
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+)
+
+// defaultMaskedPaths are hidden from containers, as in the OCI runtime spec
+// defaults docker uses
+var defaultMaskedPaths = []string{
+	"/proc/asound",
+	"/proc/acpi",
+	"/proc/kcore",
+	"/proc/keys",
+	"/proc/latency_stats",
+	"/proc/timer_list",
+	"/proc/timer_stats",
+	"/proc/sched_debug",
+	"/proc/scsi",
+	"/sys/firmware",
+	"/sys/devices/virtual/powercap",
+}
+
+var defaultReadonlyPaths = []string{
+	"/proc/bus",
+	"/proc/fs",
+	"/proc/irq",
+	"/proc/sys",
+	"/proc/sysrq-trigger",
+}
+
+// parseUnmaskOption parses the value of --security-opt unmask=, ALL or a
+// colon separated list of masked or read-only paths.
+func parseUnmaskOption(value string) ([]string, error) {
+	if value == "ALL" {
+		return append(append([]string{}, defaultMaskedPaths...), defaultReadonlyPaths...), nil
+	}
+	var paths []string
+	for _, path := range filepath.SplitList(value) {
+		path = filepath.Clean(path)
+		if !stringInSlice(path, defaultMaskedPaths) && !stringInSlice(path, defaultReadonlyPaths) {
+			return nil, fmt.Errorf("%s is not a masked or read-only path", path)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// getContainerPathMasks returns the paths to mask and to make read-only in a
+// container, privileged containers get none.
+func getContainerPathMasks(unmask []string, privileged bool) ([]string, []string) {
+	var maskedPaths, readonlyPaths []string
+	if privileged {
+		return maskedPaths, readonlyPaths
+	}
+	for _, path := range defaultMaskedPaths {
+		if !stringInSlice(path, unmask) {
+			maskedPaths = append(maskedPaths, path)
+		}
+	}
+	for _, path := range defaultReadonlyPaths {
+		if !stringInSlice(path, unmask) {
+			readonlyPaths = append(readonlyPaths, path)
+		}
+	}
+	return maskedPaths, readonlyPaths
+}
+
+// maskPath hides path with /dev/null, or an empty read-only tmpfs when it is
+// a directory. Paths the kernel does not provide are skipped.
+func maskPath(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return unix.Mount("tmpfs", path, "tmpfs", unix.MS_RDONLY, "size=0")
+	}
+	return unix.Mount("/dev/null", path, "", unix.MS_BIND, "")
+}
+
+func readonlyPath(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := unix.Mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+	return remountBind(path, unix.MS_RDONLY|unix.MS_REC)
+}
+
+// applyPathMasks runs after changing into the container root, the read-only
+// paths go first so that masks below them still apply.
+func applyPathMasks(maskedPaths []string, readonlyPaths []string) error {
+	for _, path := range readonlyPaths {
+		if err := readonlyPath(path); err != nil {
+			return fmt.Errorf("make %s read-only failed: %v", path, err)
+		}
+	}
+	for _, path := range maskedPaths {
+		if err := maskPath(path); err != nil {
+			return fmt.Errorf("mask %s failed: %v", path, err)
+		}
+	}
+	return nil
+}
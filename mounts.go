@@ -0,0 +1,439 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	mountTypeBind   = "bind"
+	mountTypeVolume = "volume"
+	mountTypeTmpfs  = "tmpfs"
+)
+
+const defaultShmSize = 64 * 1024 * 1024
+
+type mountConfig struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Target      string `json:"target"`
+	ReadOnly    bool   `json:"readOnly"`
+	Propagation string `json:"propagation"`
+	NoSuid      bool   `json:"noSuid"`
+	NoDev       bool   `json:"noDev"`
+	NoExec      bool   `json:"noExec"`
+	Data        string `json:"data,omitempty"`
+}
+
+type systemMount struct {
+	source string
+	target string
+	fstype string
+	flags  uintptr
+	data   string
+}
+
+var mountPropagationFlags = map[string]uintptr{
+	"private":  unix.MS_PRIVATE,
+	"rprivate": unix.MS_PRIVATE | unix.MS_REC,
+	"shared":   unix.MS_SHARED,
+	"rshared":  unix.MS_SHARED | unix.MS_REC,
+	"slave":    unix.MS_SLAVE,
+	"rslave":   unix.MS_SLAVE | unix.MS_REC,
+}
+
+func applyMountOption(mount *mountConfig, option string) error {
+	switch option {
+	case "ro", "readonly":
+		mount.ReadOnly = true
+	case "rw":
+		mount.ReadOnly = false
+	case "nosuid":
+		mount.NoSuid = true
+	case "nodev":
+		mount.NoDev = true
+	default:
+		if _, ok := mountPropagationFlags[option]; !ok {
+			return fmt.Errorf("invalid mount option: %s", option)
+		}
+		mount.Propagation = option
+	}
+	return nil
+}
+
+// parseVolumeOption parses a -v src:dst[:opts] spec, opts is a comma
+// separated list of ro, rw, nosuid, nodev and a propagation mode.
+func parseVolumeOption(spec string) (mountConfig, error) {
+	mount := mountConfig{Type: mountTypeBind}
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return mount, fmt.Errorf("invalid volume spec: %s", spec)
+	}
+	mount.Source, mount.Target = parts[0], parts[1]
+	if len(parts) == 3 {
+		for _, option := range strings.Split(parts[2], ",") {
+			if err := applyMountOption(&mount, option); err != nil {
+				return mount, err
+			}
+		}
+	}
+	if !filepath.IsAbs(mount.Source) {
+		mount.Type = mountTypeVolume
+		return mount, validateMount(mount)
+	}
+	if err := createDirsIfNotExist([]string{mount.Source}); err != nil {
+		return mount, err
+	}
+	return mount, validateMount(mount)
+}
+
+// parseMountOption parses a --mount type=bind,source=..,target=.. spec.
+func parseMountOption(spec string) (mountConfig, error) {
+	mount := mountConfig{Type: mountTypeBind}
+	for _, field := range strings.Split(spec, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		key, value := kv[0], ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		switch key {
+		case "type":
+			mount.Type = value
+		case "source", "src":
+			mount.Source = value
+		case "target", "destination", "dst":
+			mount.Target = value
+		case "readonly", "ro":
+			mount.ReadOnly = len(kv) == 1 || value == "true" || value == "1"
+		case "bind-propagation":
+			if _, ok := mountPropagationFlags[value]; !ok {
+				return mount, fmt.Errorf("invalid bind propagation: %s", value)
+			}
+			mount.Propagation = value
+		case "nosuid", "nodev":
+			if err := applyMountOption(&mount, key); err != nil {
+				return mount, err
+			}
+		default:
+			return mount, fmt.Errorf("unknown mount option: %s", key)
+		}
+	}
+	switch mount.Type {
+	case mountTypeBind:
+		if _, err := os.Stat(mount.Source); err != nil {
+			return mount, fmt.Errorf("invalid mount source: %v", err)
+		}
+	case mountTypeVolume:
+	case mountTypeTmpfs:
+		mount.Source = "tmpfs"
+	default:
+		return mount, fmt.Errorf("unsupported mount type: %s", mount.Type)
+	}
+	return mount, validateMount(mount)
+}
+
+func validateMount(mount mountConfig) error {
+	if mount.Type == mountTypeBind && !filepath.IsAbs(mount.Source) {
+		return fmt.Errorf("bind mount source must be an absolute path: %s", mount.Source)
+	}
+	if mount.Type == mountTypeVolume && len(mount.Source) > 0 {
+		if err := validateVolumeName(mount.Source); err != nil {
+			return err
+		}
+	}
+	if !filepath.IsAbs(mount.Target) {
+		return fmt.Errorf("mount target must be an absolute path: %s", mount.Target)
+	}
+	if filepath.Clean(mount.Target) == "/" {
+		return fmt.Errorf("cannot mount over the container root")
+	}
+	return nil
+}
+
+// parseByteSize parses sizes like 512, 64k, 128m or 1g into bytes.
+func parseByteSize(size string) (int64, error) {
+	units := map[byte]int64{'b': 1, 'k': 1 << 10, 'm': 1 << 20, 'g': 1 << 30}
+	value := strings.TrimSuffix(strings.ToLower(size), "b")
+	multiplier := int64(1)
+	if len(value) > 0 {
+		if unit, ok := units[value[len(value)-1]]; ok {
+			multiplier = unit
+			value = value[:len(value)-1]
+		}
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size: %s", size)
+	}
+	return number * multiplier, nil
+}
+
+// parseTmpfsOption parses a --tmpfs path[:options] spec, options are the
+// tmpfs size, mode, uid, gid and nr_inodes settings plus mount flags.
+func parseTmpfsOption(spec string) (mountConfig, error) {
+	mount := mountConfig{Type: mountTypeTmpfs, Source: "tmpfs", NoSuid: true, NoDev: true, NoExec: true}
+	parts := strings.SplitN(spec, ":", 2)
+	mount.Target = parts[0]
+	var data []string
+	if len(parts) == 2 {
+		for _, option := range strings.Split(parts[1], ",") {
+			key := strings.SplitN(option, "=", 2)[0]
+			switch key {
+			case "ro", "rw", "nosuid", "nodev":
+				applyMountOption(&mount, key)
+			case "suid":
+				mount.NoSuid = false
+			case "dev":
+				mount.NoDev = false
+			case "exec":
+				mount.NoExec = false
+			case "noexec":
+				mount.NoExec = true
+			case "size":
+				size, err := parseByteSize(strings.TrimPrefix(option, "size="))
+				if err != nil {
+					return mount, err
+				}
+				data = append(data, "size="+strconv.FormatInt(size, 10))
+			case "mode", "uid", "gid", "nr_inodes":
+				data = append(data, option)
+			default:
+				return mount, fmt.Errorf("invalid tmpfs option: %s", option)
+			}
+		}
+	}
+	mount.Data = strings.Join(data, ",")
+	return mount, validateMount(mount)
+}
+
+func parseMountOptions(volumes []string, mounts []string, tmpfs []string) ([]mountConfig, error) {
+	var result []mountConfig
+	for _, spec := range tmpfs {
+		mount, err := parseTmpfsOption(spec)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mount)
+	}
+	for _, volume := range volumes {
+		mount, err := parseVolumeOption(volume)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mount)
+	}
+	for _, spec := range mounts {
+		mount, err := parseMountOption(spec)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mount)
+	}
+	return result, nil
+}
+
+// secureJoin joins path onto root resolving symlinks as if root was "/",
+// so a symlink inside the container can not point the result outside of it.
+func secureJoin(root, path string) (string, error) {
+	var resolved string
+	pending := strings.Split(filepath.Clean("/"+path), "/")
+	for links := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = filepath.Dir(resolved)
+			if resolved == "." {
+				resolved = ""
+			}
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > 255 {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+		}
+		link, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(link) {
+			resolved = ""
+		}
+		pending = append(strings.Split(link, "/"), pending...)
+	}
+	return filepath.Join(root, "/"+resolved), nil
+}
+
+func createMountTarget(target string, isDir bool) error {
+	if isDir {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		return file.Close()
+	}
+	return nil
+}
+
+// remountBind changes the flags of an existing bind mount, keeping the
+// nosuid, nodev and noexec flags it already has since those can be locked.
+func remountBind(target string, flags uintptr) error {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(target, &stat); err != nil {
+		return err
+	}
+	for statFlag, mountFlag := range map[int64]uintptr{
+		unix.ST_NOSUID: unix.MS_NOSUID,
+		unix.ST_NODEV:  unix.MS_NODEV,
+		unix.ST_NOEXEC: unix.MS_NOEXEC,
+	} {
+		if int64(stat.Flags)&statFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+	return unix.Mount("", target, "", unix.MS_BIND|unix.MS_REMOUNT|flags, "")
+}
+
+func getMountFlags(mount mountConfig) uintptr {
+	var flags uintptr
+	if mount.ReadOnly {
+		flags |= unix.MS_RDONLY
+	}
+	if mount.NoSuid {
+		flags |= unix.MS_NOSUID
+	}
+	if mount.NoDev {
+		flags |= unix.MS_NODEV
+	}
+	if mount.NoExec {
+		flags |= unix.MS_NOEXEC
+	}
+	return flags
+}
+
+func mountTmpfs(rootfs string, mount mountConfig) error {
+	target, err := secureJoin(rootfs, mount.Target)
+	if err != nil {
+		return err
+	}
+	if err := createMountTarget(target, true); err != nil {
+		return err
+	}
+	return unix.Mount("tmpfs", target, "tmpfs", getMountFlags(mount), mount.Data)
+}
+
+func mountBind(rootfs string, mount mountConfig) error {
+	if mount.Type == mountTypeVolume {
+		if err := populateVolume(rootfs, mount); err != nil {
+			return err
+		}
+		mount.Source = getVolumeDataPath(mount.Source)
+	}
+	info, err := os.Stat(mount.Source)
+	if err != nil {
+		return err
+	}
+	target, err := secureJoin(rootfs, mount.Target)
+	if err != nil {
+		return err
+	}
+	if err := createMountTarget(target, info.IsDir()); err != nil {
+		return err
+	}
+	if err := unix.Mount(mount.Source, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+
+	if flags := getMountFlags(mount); flags != 0 {
+		if err := remountBind(target, flags); err != nil {
+			return err
+		}
+	}
+
+	propagation := mount.Propagation
+	if len(propagation) == 0 {
+		propagation = "rprivate"
+	}
+	return unix.Mount("", target, "", mountPropagationFlags[propagation], "")
+}
+
+func getSystemMounts(config containerConfig) []systemMount {
+	shmSize := config.ShmSize
+	if shmSize <= 0 {
+		shmSize = defaultShmSize
+	}
+	devptsOptions := "newinstance,ptmxmode=0666,mode=0620,gid=5"
+	if _, ok := getHostId(config.GidMappings, 5); len(config.GidMappings) > 0 && !ok {
+		// the tty group is not mapped when only the rootless user is
+		devptsOptions = "newinstance,ptmxmode=0666,mode=0620"
+	}
+	var sysfsFlags uintptr = unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC
+	if !config.Privileged {
+		sysfsFlags |= unix.MS_RDONLY
+	}
+	mounts := []systemMount{
+		{"proc", "/proc", "proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+		{"sysfs", "/sys", "sysfs", sysfsFlags, ""},
+		{"tmpfs", "/dev", "tmpfs", unix.MS_NOSUID | unix.MS_STRICTATIME, "mode=755,size=65536k"},
+		{"devpts", "/dev/pts", "devpts", unix.MS_NOSUID | unix.MS_NOEXEC, devptsOptions},
+		{"shm", "/dev/shm", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, "mode=1777,size=" + strconv.FormatInt(shmSize, 10)},
+		{"mqueue", "/dev/mqueue", "mqueue", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+	}
+	for _, mount := range config.Mounts {
+		if filepath.Clean(mount.Target) == "/tmp" {
+			return mounts
+		}
+	}
+	// /tmp stays executable like on a host, but gets no devices or setuid binaries
+	return append(mounts, systemMount{"tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "mode=1777,size=65536k"})
+}
+
+// mountContainerFileSystems mounts the kernel file systems and then the
+// container mounts on top of rootfs, it runs inside the container mount
+// namespace before changing root.
+func mountContainerFileSystems(rootfs string, config containerConfig) error {
+	for _, mount := range getSystemMounts(config) {
+		target, err := secureJoin(rootfs, mount.target)
+		if err != nil {
+			return err
+		}
+		if err := createDirsIfNotExist([]string{target}); err != nil {
+			return err
+		}
+		if err := unix.Mount(mount.source, target, mount.fstype, mount.flags, mount.data); err != nil {
+			return fmt.Errorf("mount %s on %s failed: %v", mount.fstype, mount.target, err)
+		}
+	}
+	if err := createContainerDevices(rootfs); err != nil {
+		return fmt.Errorf("create devices failed: %v", err)
+	}
+	for _, mount := range config.Mounts {
+		var err error
+		if mount.Type == mountTypeTmpfs {
+			err = mountTmpfs(rootfs, mount)
+		} else {
+			err = mountBind(rootfs, mount)
+		}
+		if err != nil {
+			return fmt.Errorf("mount %s on %s failed: %v", mount.Source, mount.Target, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+)
+
+var validContainerName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+var nameAdjectives = []string{
+	"admiring", "agitated", "amazing", "bold", "brave", "busy", "clever", "compassionate",
+	"confident", "cool", "dazzling", "determined", "eager", "ecstatic", "elegant", "epic",
+	"festive", "focused", "friendly", "gallant", "gifted", "happy", "hopeful", "jolly",
+	"keen", "kind", "laughing", "lucid", "modest", "nifty", "optimistic", "peaceful",
+	"pensive", "quirky", "relaxed", "serene", "sharp", "stoic", "tender", "vibrant",
+	"wizardly", "youthful", "zealous",
+}
+
+var nameSurnames = []string{
+	"babbage", "bardeen", "bell", "curie", "darwin", "dijkstra", "einstein", "euler",
+	"faraday", "fermat", "feynman", "galileo", "gauss", "goldberg", "hamilton", "hawking",
+	"hopper", "hypatia", "kepler", "knuth", "lamport", "liskov", "lovelace", "mccarthy",
+	"meitner", "newton", "noether", "pascal", "pike", "ritchie", "shannon", "thompson",
+	"torvalds", "turing", "wozniak", "yalow",
+}
+
+func isContainerNameUsed(name string) bool {
+	states, _ := getContainerStates()
+	for _, state := range states {
+		if state.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func createContainerName() string {
+	for i := 0; ; i++ {
+		name := nameAdjectives[rand.Intn(len(nameAdjectives))] + "_" + nameSurnames[rand.Intn(len(nameSurnames))]
+		if i > 10 {
+			name += fmt.Sprintf("%d", rand.Intn(10))
+		}
+		if !isContainerNameUsed(name) {
+			return name
+		}
+	}
+}
+
+func validateContainerName(name string) error {
+	if !validContainerName.MatchString(name) {
+		return fmt.Errorf("invalid container name %q, only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	if isContainerNameUsed(name) {
+		return fmt.Errorf("container name %q is already in use", name)
+	}
+	return nil
+}
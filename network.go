@@ -7,6 +7,8 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"runtime"
+	"strconv"
 )
 
 func isGockerBridgeUp() (bool, error) {
@@ -23,6 +25,14 @@ func isGockerBridgeUp() (bool, error) {
 	}
 }
 
+func ensureGockerBridge() {
+	if isUp, _ := isGockerBridgeUp(); !isUp {
+		if err := setupGockerBridge(); err != nil {
+			log.Fatalf("set up gocker bridge failed: %v\n", err)
+		}
+	}
+}
+
 func setupVirtualEthOnHost(containerId string) error {
 	veth0 := "veth0_" + containerId[:6]
 	veth1 := "veth1_" + containerId[:6]
@@ -42,6 +52,15 @@ func setupVirtualEthOnHost(containerId string) error {
 	return nil
 }
 
+func removeVirtualEthOnHost(containerId string) {
+	veth0 := "veth0_" + containerId[:6]
+	if link, err := netlink.LinkByName(veth0); err == nil {
+		if err := netlink.LinkDel(link); err != nil {
+			log.Printf("remove %s failed: %v\n", veth0, err)
+		}
+	}
+}
+
 func setupNewNetworkNamespace(containerId string) {
 	createDirsIfNotExist([]string{getGockerNetNsPath()})
 	nsMount := getGockerNetNsPath() + "/" + containerId
@@ -65,6 +84,22 @@ func setupNewNetworkNamespace(containerId string) {
 	}
 }
 
+// bindContainerNetworkNamespace keeps the network namespace a container in a
+// user namespace created for itself reachable under the same path as the ones
+// setupNewNetworkNamespace creates, so it is owned by the container's root.
+func bindContainerNetworkNamespace(containerId string, pid int) error {
+	if err := createDirsIfNotExist([]string{getGockerNetNsPath()}); err != nil {
+		return err
+	}
+	nsMount := getGockerNetNsPath() + "/" + containerId
+	fd, err := unix.Open(nsMount, unix.O_RDONLY|unix.O_CREAT|unix.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	unix.Close(fd)
+	return unix.Mount("/proc/"+strconv.Itoa(pid)+"/ns/net", nsMount, "bind", unix.MS_BIND, "")
+}
+
 func createMACAddress() net.HardwareAddr {
 	hw := make(net.HardwareAddr, 6)
 	hw[0] = 0x02
@@ -91,6 +126,7 @@ func setupContainerNetworkInterfaceStep1(containerId string) {
 }
 
 func setupContainerNetworkInterfaceStep2(containerId string) {
+	runtime.LockOSThread()
 	nsMount := getGockerNetNsPath() + "/" + containerId
 	fd, err := unix.Open(nsMount, unix.O_RDONLY, 0)
 	defer unix.Close(fd)
@@ -126,11 +162,14 @@ func setupContainerNetworkInterfaceStep2(containerId string) {
 }
 
 func joinContainerNetworkNamespace(containerId string) error {
+	// setns only moves the calling thread, keep the rest of the process on it
+	runtime.LockOSThread()
 	nsMount := getGockerNetNsPath() + "/" + containerId
 	fd, err := unix.Open(nsMount, unix.O_RDONLY, 0)
 	if err != nil {
 		return err
 	}
+	defer unix.Close(fd)
 	if err := unix.Setns(fd, unix.CLONE_NEWNET); err != nil {
 		return err
 	}
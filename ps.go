@@ -5,124 +5,84 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-type runningContainerInfo struct {
-	containerId string
-	image       string
-	command     string
-	pid         int
-}
-
-func getRunningContainers() ([]runningContainerInfo, error) {
-	var containers []runningContainerInfo
-	path := "/sys/fs/cgroup/cpu/gocker"
-
-	entries, err := os.ReadDir(path)
-	if os.IsNotExist(err) {
-		return containers, err
-	} else {
-		for _, entry := range entries {
-			if entry.IsDir() {
-				container, _ := getRunningContainerInfoForId(entry.Name())
-				if container.pid > 0 {
-					containers = append(containers, container)
-				}
-			}
-		}
-		return containers, nil
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 || end+2 >= len(stat) {
+		return false
 	}
+	return stat[end+2] != 'Z'
 }
 
-func getRunningContainerInfoForId(containerId string) (runningContainerInfo, error) {
-	container := runningContainerInfo{}
-	var procs []string
-	path := "/sys/fs/cgroup/cpu/gocker"
-
-	file, err := os.Open(path + "/" + containerId + "/cgroup.procs")
+func getContainerPids(containerId string) ([]int, error) {
+	var pids []int
+	file, err := os.Open("/sys/fs/cgroup/cpu/gocker/" + containerId + "/cgroup.procs")
 	if err != nil {
-		return container, err
+		return pids, err
 	}
 	defer file.Close()
 	scanner := bufio.NewScanner(file)
 	scanner.Split(bufio.ScanLines)
 	for scanner.Scan() {
-		procs = append(procs, scanner.Text())
-	}
-	if len(procs) > 0 {
-		pid, err := strconv.Atoi(procs[len(procs)-1])
-		if err != nil {
-			return container, err
-		}
-		cmd, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/exe")
-		if err != nil {
-			log.Println("read command link failed")
-		}
-		containerMntPath := getGockerContainersPath() + "/" + containerId + "/fs/mnt"
-		realContainerMntPath, err := filepath.EvalSymlinks(containerMntPath)
+		pid, err := strconv.Atoi(scanner.Text())
 		if err != nil {
-			log.Println("resolve path failed")
-		}
-
-		image, _ := getDistribution(containerId)
-		container = runningContainerInfo{
-			containerId: containerId,
-			image:       image,
-			command:     cmd[len(realContainerMntPath):],
-			pid:         pid,
+			return pids, err
 		}
+		pids = append(pids, pid)
 	}
-	return container, nil
+	return pids, scanner.Err()
 }
 
-func getDistribution(containerId string) (string, error) {
-	var lines []string
-	file, err := os.Open("/proc/mounts")
+func getRunningContainers() ([]containerState, error) {
+	var containers []containerState
+	states, err := getContainerStates()
 	if err != nil {
-		log.Println("read /proc/mounts failed")
-		return "", err
+		return containers, err
 	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	for _, state := range states {
+		if isContainerRunning(state) {
+			containers = append(containers, state)
+		}
 	}
+	return containers, nil
+}
 
-	for _, line := range lines {
-		if strings.Contains(line, containerId) {
-			parts := strings.Split(line, " ")
-			for _, part := range parts {
-				if strings.Contains(part, "lowerdir=") {
-					options := strings.Split(part, ",")
-					for _, option := range options {
-						if strings.Contains(option, "lowerdir=") {
-							imagePath := getGockerImagesPath()
-							leaderString := "lowerdir=" + imagePath + "/"
-							trailerString := option[len(leaderString):]
-							imageId := trailerString[:12]
-							imgName, tagName := imageExistByHash(imageId)
-							return fmt.Sprintf("%s:%s", imgName, tagName), nil
-						}
-					}
-				}
-			}
-		}
+func getContainerStatus(container containerState) string {
+	if isContainerRunning(container) {
+		return containerStatusRunning
 	}
-	return "", nil
+	if container.Status == containerStatusCreated {
+		return containerStatusCreated
+	}
+	return fmt.Sprintf("%s (%d)", containerStatusExited, container.ExitCode)
 }
 
-func printRunningContainers() {
-	containers, err := getRunningContainers()
+func printContainers(all bool) {
+	var containers []containerState
+	var err error
+	if all {
+		containers, err = getContainerStates()
+	} else {
+		containers, err = getRunningContainers()
+	}
 	if err != nil {
-		log.Fatalln("get running containers failed")
+		log.Fatalln("get containers failed")
 	}
 
-	fmt.Println("CONTAINER ID\tIMAGE\t\tCOMMAND")
+	fmt.Println("CONTAINER ID\tIMAGE\t\tSTATUS\t\tNAMES\t\t\tCOMMAND")
 	for _, container := range containers {
-		fmt.Printf("%s\t%s\t\t%s\n", container.containerId, container.image, container.command)
+		fmt.Printf("%s\t%s\t\t%s\t\t%-16s\t%s\n", container.Id, container.Image,
+			getContainerStatus(container), container.Name, strings.Join(container.Command, " "))
 	}
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func removeContainerHome(containerId string) {
+	if config, err := loadContainerConfig(containerId); err == nil {
+		defer removeAnonymousVolumes(config.Mounts)
+	}
+	if err := removeAll(getContainerHome(containerId)); err != nil {
+		log.Printf("remove container dir failed: %v\n", err)
+	}
+}
+
+func removeContainer(containerId string, force bool) {
+	container, err := loadContainerState(containerId)
+	if err != nil {
+		log.Fatalln("no such container")
+	}
+	if isContainerRunning(container) {
+		if !force {
+			log.Fatalf("container %s is running, stop it first or use -f\n", containerId)
+		}
+		forceKillContainer(container)
+	}
+	cleanupContainer(containerId)
+	removeContainerHome(containerId)
+	fmt.Println(containerId)
+}
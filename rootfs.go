@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"strings"
+)
+
+const defaultRootfsPropagation = "rprivate"
+
+func validateRootfsPropagation(propagation string) error {
+	if _, ok := mountPropagationFlags[propagation]; !ok {
+		return fmt.Errorf("invalid rootfs propagation: %s", propagation)
+	}
+	return nil
+}
+
+// prepareRootPropagation stops mounts made in the container mount namespace
+// from propagating back to the host, it runs before any container mount.
+func prepareRootPropagation(propagation string) error {
+	flags := uintptr(unix.MS_PRIVATE)
+	if !strings.HasSuffix(propagation, "private") {
+		flags = unix.MS_SLAVE
+	}
+	return unix.Mount("", "/", "", unix.MS_REC|flags, "")
+}
+
+// bindRootfs mounts rootfs onto itself in the container mount namespace, the
+// overlay mounted by the supervisor is locked in a user namespace and can not
+// be pivoted to.
+func bindRootfs(rootfs string) error {
+	return unix.Mount(rootfs, rootfs, "bind", unix.MS_BIND|unix.MS_REC, "")
+}
+
+func isRootOnRamfs() bool {
+	var stat unix.Statfs_t
+	if err := unix.Statfs("/", &stat); err != nil {
+		return false
+	}
+	return uint32(stat.Type) == uint32(unix.RAMFS_MAGIC)
+}
+
+// pivotRoot makes rootfs the root of the mount namespace and detaches the
+// old root, pivoting onto "." avoids creating a put_old directory in rootfs.
+func pivotRoot(rootfs string) error {
+	oldRoot, err := unix.Open("/", unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(oldRoot)
+	if err := os.Chdir(rootfs); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root failed: %v", err)
+	}
+	// the old root is now mounted on top of the new one
+	if err := unix.Fchdir(oldRoot); err != nil {
+		return err
+	}
+	if err := unix.Mount("", ".", "", unix.MS_SLAVE|unix.MS_REC, ""); err != nil {
+		return err
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root failed: %v", err)
+	}
+	return os.Chdir("/")
+}
+
+// changeContainerRoot switches to rootfs with pivot_root, or chroot when the
+// current root is a ramfs that can not be pivoted away from, and then applies
+// the requested propagation to the new root.
+func changeContainerRoot(rootfs string, propagation string) error {
+	if isRootOnRamfs() {
+		if err := unix.Chroot(rootfs); err != nil {
+			return err
+		}
+		return os.Chdir("/")
+	}
+	if err := pivotRoot(rootfs); err != nil {
+		return err
+	}
+	return unix.Mount("", "/", "", mountPropagationFlags[propagation], "")
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// rootlessEnv is passed on to the helpers gocker executes itself as, which
+	// run as root of a user namespace but keep to the rootless paths
+	rootlessEnv = "GOCKER_ROOTLESS"
+	// mappedReexecEnv marks a process executed again once its root was mapped
+	mappedReexecEnv = "GOCKER_MAPPED_REEXEC"
+)
+
+func isRootless() bool {
+	return os.Getuid() != 0 || os.Getenv(rootlessEnv) == "1"
+}
+
+// useRootlessPaths keeps images and volumes under $XDG_DATA_HOME and the
+// container state under $XDG_RUNTIME_DIR.
+func useRootlessPaths() {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if len(dataHome) == 0 {
+		home, err := os.UserHomeDir()
+		doOrDieWithMsg(err, "find home directory failed")
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if len(runtimeDir) == 0 {
+		// exported so that the helpers, which see themselves as root, agree
+		runtimeDir = filepath.Join(os.TempDir(), "gocker-run-"+strconv.Itoa(os.Getuid()))
+		doOrDieWithMsg(os.MkdirAll(runtimeDir, 0700), "create runtime dir failed")
+		os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	}
+	gockerHomePath = filepath.Join(dataHome, "gocker")
+	gockerImagesPath = gockerHomePath + "/images"
+	gockerTempPath = gockerHomePath + "/tmp"
+	gockerVolumesPath = gockerHomePath + "/volumes"
+	gockerContainersPath = filepath.Join(runtimeDir, "gocker", "containers")
+	gockerNetNsPath = filepath.Join(runtimeDir, "gocker", "net-ns")
+}
+
+func hasIdMapTools() bool {
+	for _, tool := range []string{"newuidmap", "newgidmap"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// getRootlessMappings maps the container root to the current user and the
+// following ids to the user's subordinate ids. Without newuidmap and
+// newgidmap, or subordinate ids, only the container root is mapped.
+func getRootlessMappings() ([]idMapping, []idMapping) {
+	uidMappings := []idMapping{{ContainerId: 0, HostId: os.Getuid(), Size: 1}}
+	gidMappings := []idMapping{{ContainerId: 0, HostId: os.Getgid(), Size: 1}}
+	if !hasIdMapTools() {
+		log.Println("newuidmap and newgidmap not found, only root is mapped in the container")
+		return uidMappings, gidMappings
+	}
+	name, uid := getRemapUser(strconv.Itoa(os.Getuid()))
+	subUids, uidErr := parseSubordinateIds("/etc/subuid", name, uid)
+	subGids, gidErr := parseSubordinateIds("/etc/subgid", name, uid)
+	if uidErr != nil || gidErr != nil {
+		log.Printf("no subordinate ids for %s, only root is mapped in the container\n", name)
+		return uidMappings, gidMappings
+	}
+	for _, mapping := range subUids {
+		mapping.ContainerId++
+		uidMappings = append(uidMappings, mapping)
+	}
+	for _, mapping := range subGids {
+		mapping.ContainerId++
+		gidMappings = append(gidMappings, mapping)
+	}
+	return uidMappings, gidMappings
+}
+
+// usesIdMapTools reports whether the mappings can only be written by
+// newuidmap and newgidmap, an unprivileged process may only map itself.
+func usesIdMapTools(uidMappings []idMapping, gidMappings []idMapping) bool {
+	return len(uidMappings) > 1 || len(gidMappings) > 1
+}
+
+// setRootlessUserNamespaceAttr makes cmd start in a new user namespace with
+// the rootless mappings. Mappings that need newuidmap are left to
+// writeRootlessIdMappings once it started.
+func setRootlessUserNamespaceAttr(attr *unix.SysProcAttr, uidMappings []idMapping, gidMappings []idMapping) {
+	attr.Cloneflags |= unix.CLONE_NEWUSER
+	if usesIdMapTools(uidMappings, gidMappings) {
+		return
+	}
+	attr.UidMappings = toSysProcIDMaps(uidMappings)
+	attr.GidMappings = toSysProcIDMaps(gidMappings)
+	attr.GidMappingsEnableSetgroups = false
+	attr.Credential = &syscall.Credential{Uid: 0, Gid: 0, NoSetGroups: true}
+}
+
+func runIdMapTool(tool string, pid int, mappings []idMapping) error {
+	args := []string{strconv.Itoa(pid)}
+	for _, mapping := range mappings {
+		args = append(args, strconv.Itoa(mapping.ContainerId), strconv.Itoa(mapping.HostId), strconv.Itoa(mapping.Size))
+	}
+	if out, err := exec.Command(tool, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %v: %s", tool, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func writeRootlessIdMappings(pid int, uidMappings []idMapping, gidMappings []idMapping) error {
+	if !usesIdMapTools(uidMappings, gidMappings) {
+		return nil
+	}
+	if err := runIdMapTool("newuidmap", pid, uidMappings); err != nil {
+		return err
+	}
+	return runIdMapTool("newgidmap", pid, gidMappings)
+}
+
+// reexecMappedProcess executes the current process again when it has no
+// capabilities because it was started before newuidmap mapped its root,
+// execve only grants the capabilities of a user namespace to a mapped root.
+func reexecMappedProcess() error {
+	data, err := getThreadCapabilities()
+	if err != nil {
+		return err
+	}
+	if data[0].Effective != 0 || data[1].Effective != 0 {
+		return nil
+	}
+	return unix.Exec("/proc/self/exe", os.Args, append(os.Environ(), mappedReexecEnv+"=1"))
+}
+
+// waitForMappedSetup waits for the parent to set the process up and makes
+// sure it runs with the capabilities of its user namespace root.
+func waitForMappedSetup() error {
+	// the first execution already waited
+	if os.Getenv(mappedReexecEnv) == "1" {
+		return nil
+	}
+	if err := waitForContainerSetup(); err != nil {
+		return err
+	}
+	return reexecMappedProcess()
+}
+
+// startInRootlessUserNamespace starts cmd as root of a user namespace with
+// the rootless mappings, it waits in waitForMappedSetup until they are set.
+func startInRootlessUserNamespace(cmd *exec.Cmd) error {
+	uidMappings, gidMappings := getRootlessMappings()
+	syncReader, syncWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer syncWriter.Close()
+	cmd.ExtraFiles = []*os.File{syncReader}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &unix.SysProcAttr{}
+	}
+	setRootlessUserNamespaceAttr(cmd.SysProcAttr, uidMappings, gidMappings)
+	err = cmd.Start()
+	syncReader.Close()
+	if err != nil {
+		return err
+	}
+	if err := writeRootlessIdMappings(cmd.Process.Pid, uidMappings, gidMappings); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	_, err = syncWriter.Write([]byte{0})
+	return err
+}
+
+// removeAll removes path like os.RemoveAll, in rootless mode what the
+// subordinate ids own is removed from a user namespace mapping them.
+func removeAll(path string) error {
+	err := os.RemoveAll(path)
+	if err == nil || !isRootless() || !os.IsPermission(err) || !hasIdMapTools() {
+		return err
+	}
+	cmd := exec.Command("/proc/self/exe", "rootless-rm", path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := startInRootlessUserNamespace(cmd); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+func runRootlessRemove(paths []string) {
+	doOrDieWithMsg(waitForMappedSetup(), "wait for user namespace setup failed")
+	for _, path := range paths {
+		doOrDieWithMsg(os.RemoveAll(path), "remove "+path+" failed")
+	}
+}
+
+func isInUserNamespaceOf(pid int) bool {
+	self, err := os.Readlink("/proc/self/ns/user")
+	if err != nil {
+		return false
+	}
+	other, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/ns/user")
+	return err == nil && self == other
+}
+
+// runInUserNamespaceOf runs gocker again with args as root of the user
+// namespace of pid, the container namespaces can only be joined from there.
+// A multi-threaded process can not join a user namespace, so nsenter does,
+// keeping the credentials of the user that is mapped to the container root.
+func runInUserNamespaceOf(pid int, args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("nsenter", append([]string{"--target", strconv.Itoa(pid), "--user", "--preserve-credentials", "--", self}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// signalContainerProcess sends sig to a container process, a rootless user
+// can only signal the processes running as the container root directly.
+func signalContainerProcess(pid int, sig syscall.Signal) error {
+	err := unix.Kill(pid, sig)
+	if err != unix.EPERM || !isRootless() || isInUserNamespaceOf(pid) {
+		return err
+	}
+	return runInUserNamespaceOf(pid, []string{"signal-process", strconv.Itoa(pid), strconv.Itoa(int(sig))})
+}
+
+func runSignalProcess(args []string) {
+	if len(args) < 2 {
+		log.Fatalln("pid and signal are needed")
+	}
+	pid, err := strconv.Atoi(args[0])
+	doOrDieWithMsg(err, "parse pid failed")
+	sig, err := strconv.Atoi(args[1])
+	doOrDieWithMsg(err, "parse signal failed")
+	doOrDieWithMsg(unix.Kill(pid, syscall.Signal(sig)), "send signal failed")
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"fmt"
 	"golang.org/x/sys/unix"
+	"io"
 	"log"
 	"math/rand"
 	"os"
 	"os/exec"
-	"strconv"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 )
 
 func createContainerId() string {
@@ -19,15 +22,20 @@ func createContainerId() string {
 }
 
 func createContainerDirs(containerId string) {
-	contHome := getGockerContainersPath() + "/" + containerId
+	contHome := getContainerHome(containerId)
 	dirs := []string{contHome + "/fs", contHome + "/fs/mnt", contHome + "/fs/upperdir", contHome + "/fs/workdir"}
 	if err := createDirsIfNotExist(dirs); err != nil {
 		log.Fatalln("create container dirs failed")
 	}
 }
 
-func mountOverlayFileSystem(containerId, imageShaHex string) {
+// containerSyncFd is the descriptor child-mode waits on until the supervisor
+// has placed it in its cgroups and network, it is the first of cmd.ExtraFiles.
+const containerSyncFd = 3
+
+func mountOverlayFileSystem(containerId string, config containerConfig) {
 	var srcLayers []string
+	imageShaHex := config.ImageId
 	pathManifest := getManifestPathForImage(imageShaHex)
 	mani := manifest{}
 	parseManifest(pathManifest, &mani)
@@ -38,11 +46,25 @@ func mountOverlayFileSystem(containerId, imageShaHex string) {
 		log.Fatalln("could not handle more than one manifest")
 	}
 
+	// rootless layers are owned by the user, which is the container root
+	shiftOwners := len(config.UidMappings) > 0 && !isRootless()
 	imageBasePath := getGockerImagesPath() + "/" + imageShaHex
 	for _, layer := range mani[0].Layers {
-		srcLayers = append(srcLayers, imageBasePath+"/"+layer[:12]+"/fs")
+		layerPath := imageBasePath + "/" + layer[:12] + "/fs"
+		if shiftOwners {
+			shiftedPath, err := getShiftedLayerPath(layerPath, config.UidMappings, config.GidMappings)
+			doOrDieWithMsg(err, "shift layer ownership failed")
+			layerPath = shiftedPath
+		}
+		srcLayers = append(srcLayers, layerPath)
 	}
 	contFSHome := getContainerFSHome(containerId)
+	if shiftOwners {
+		// the upper dir is the root directory of the container
+		uid, gid, err := shiftOwner(config.UidMappings, config.GidMappings, 0, 0)
+		doOrDieWithMsg(err, "change upper dir owner failed")
+		doOrDieWithMsg(os.Chown(contFSHome+"/upperdir", uid, gid), "change upper dir owner failed")
+	}
 	mntOptions := "lowerdir=" + strings.Join(srcLayers, ":") + ",upperdir=" + contFSHome + "/upperdir,workdir=" + contFSHome + "/workdir"
 	if err := unix.Mount("none", contFSHome+"/mnt", "overlay", 0, mntOptions); err != nil {
 		log.Fatalf("mount container file system failed: %v", err)
@@ -53,95 +75,285 @@ func getContainerFSHome(containerId string) string {
 	return getGockerContainersPath() + "/" + containerId + "/fs"
 }
 
-func prepareAndExecuteContainer(mem int, swap int, pids int, cpus float64,
-	containerId string, imageShaHex string, args []string) {
+func runSetupCommand(command string, containerId string) {
 	cmd := &exec.Cmd{
 		Path:   "/proc/self/exe",
-		Args:   []string{"/proc/self/exe", "setup-netns", containerId},
+		Args:   []string{"/proc/self/exe", command, containerId},
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
 	}
 	cmd.Run()
+}
 
-	cmd = &exec.Cmd{
-		Path:   "/proc/self/exe",
-		Args:   []string{"/proc/self/exe", "setup-veth", containerId},
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
+func prepareAndExecuteContainer(containerId string, config containerConfig, attach bool) error {
+	// a container in a user namespace creates its network namespace itself,
+	// so that it is owned by the container's root
+	userns := len(config.UidMappings) > 0
+	if !userns {
+		runSetupCommand("setup-netns", containerId)
+		runSetupCommand("setup-veth", containerId)
 	}
-	cmd.Run()
 
-	var options []string
-	if mem > 0 {
-		options = append(options, "--mem="+strconv.Itoa(mem))
+	logger, err := newContainerLogger(containerId)
+	if err != nil {
+		return err
 	}
-	if swap > 0 {
-		options = append(options, "--swap="+strconv.Itoa(swap))
+	defer logger.close()
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return err
 	}
-	if pids > 0 {
-		options = append(options, "--pids="+strconv.Itoa(pids))
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	syncReader, syncWriter, err := os.Pipe()
+	if err != nil {
+		return err
 	}
-	if cpus > 0 {
-		options = append(options, "--cpus="+strconv.FormatFloat(cpus, 'f', 1, 64))
+	defer syncWriter.Close()
+
+	cmd := exec.Command("/proc/self/exe", "child-mode", containerId)
+	var stdinWriter io.WriteCloser
+	if attach && config.Interactive && !config.Tty {
+		// a pipe keeps the container off the host terminal, which would
+		// stop it with SIGTTIN from outside the foreground process group
+		if stdinWriter, err = cmd.StdinPipe(); err != nil {
+			return err
+		}
 	}
-	options = append(options, "--img="+imageShaHex)
-	args = append([]string{containerId}, args...)
-	args = append(options, args...)
-	args = append([]string{"child-mode"}, args...)
-	cmd = exec.Command("/proc/self/exe", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	// a new session keeps terminal generated signals from reaching the
+	// container directly, they are relayed by the supervisor instead
 	cmd.SysProcAttr = &unix.SysProcAttr{
 		Cloneflags: unix.CLONE_NEWPID | unix.CLONE_NEWNS | unix.CLONE_NEWIPC | unix.CLONE_NEWUTS,
+		Setsid:     true,
 	}
-	cmd.Run()
+	if userns {
+		cmd.SysProcAttr.Cloneflags |= unix.CLONE_NEWNET
+		if isRootless() {
+			setRootlessUserNamespaceAttr(cmd.SysProcAttr, config.UidMappings, config.GidMappings)
+		} else {
+			setUserNamespaceAttr(cmd.SysProcAttr, config.UidMappings, config.GidMappings)
+		}
+	}
+	cmd.ExtraFiles = []*os.File{syncReader}
+	var consoleSocket, childConsoleSocket *os.File
+	if config.Tty {
+		consoleSocket, childConsoleSocket, err = newConsoleSocketPair()
+		if err != nil {
+			return err
+		}
+		defer consoleSocket.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, childConsoleSocket)
+	}
+	err = cmd.Start()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	syncReader.Close()
+	if childConsoleSocket != nil {
+		childConsoleSocket.Close()
+	}
+	if err != nil {
+		stdoutReader.Close()
+		stderrReader.Close()
+		return err
+	}
+	defer relaySignals(cmd.Process.Pid)()
+	if isRootless() {
+		// a rootless container has no veth, only its mappings may be left
+		if err := writeRootlessIdMappings(cmd.Process.Pid, config.UidMappings, config.GidMappings); err != nil {
+			cmd.Process.Kill()
+			log.Printf("map container user namespace failed: %v\n", err)
+			return cmd.Wait()
+		}
+	} else if userns {
+		doOrDieWithMsg(bindContainerNetworkNamespace(containerId, cmd.Process.Pid), "bind container Netns failed")
+		runSetupCommand("setup-veth", containerId)
+	}
+	if canManageCGroups() {
+		createCGroups(containerId, cmd.Process.Pid, true)
+		configureCGroups(containerId, config.Mem, config.Swap, config.Pids, config.Cpus)
+	}
+	if _, err := syncWriter.Write([]byte{0}); err != nil {
+		log.Printf("release container failed: %v\n", err)
+	}
+	syncWriter.Close()
+	if stdinWriter != nil {
+		go func() {
+			io.Copy(stdinWriter, os.Stdin)
+			stdinWriter.Close()
+		}()
+	}
+	var stdout, stderr io.Writer
+	if attach {
+		stdout, stderr = os.Stdout, os.Stderr
+	}
+	logger.copyStream(logStreamStdout, stdoutReader, stdout)
+	logger.copyStream(logStreamStderr, stderrReader, stderr)
+	if err := updateContainerState(containerId, func(state *containerState) {
+		state.Pid = cmd.Process.Pid
+		state.Status = containerStatusRunning
+		state.StartedAt = time.Now()
+	}); err != nil {
+		log.Printf("update container state failed: %v\n", err)
+	}
+	if consoleSocket != nil {
+		master, err := receiveFd(consoleSocket)
+		if err != nil {
+			log.Printf("receive container console failed: %v\n", err)
+			return cmd.Wait()
+		}
+		if attach {
+			defer attachTerminal(master, config.Interactive)()
+		}
+		// prompts and echoed keystrokes must reach the terminal before a line
+		// is complete, copyStream tees them as they are read
+		logger.copyStream(logStreamStdout, master, stdout)
+	}
+	return cmd.Wait()
+}
+
+func isNotMounted(err error) bool {
+	return err == unix.EINVAL || err == unix.ENOENT
 }
 
 func unmountContainerFS(containerId string) {
-	path := getGockerContainersPath() + "/" + containerId + "/fs/mnt"
-	if err := unix.Unmount(path, 0); err != nil {
-		log.Fatalln("unmount container file system failed")
+	path := getContainerFSHome(containerId) + "/mnt"
+	if err := unix.Unmount(path, 0); err != nil && !isNotMounted(err) {
+		log.Printf("unmount container file system failed: %v\n", err)
 	}
 }
 
 func unmountNetWorkNamespace(containerId string) {
 	path := getGockerNetNsPath() + "/" + containerId
-	if err := unix.Unmount(path, 0); err != nil {
-		log.Fatalln("unmount container network namespace failed")
+	if err := unix.Unmount(path, 0); err != nil && !isNotMounted(err) {
+		log.Printf("unmount container network namespace failed: %v\n", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("remove container network namespace failed: %v\n", err)
 	}
 }
 
-func execContainerCommand(mem int, swap int, pids int, cpus float64, containerId string, imageShaHex string, args []string) {
-	mntPath := getContainerFSHome(containerId) + "/mnt"
-	cmd := exec.Command(args[0], args[1:]...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func cleanupContainer(containerId string) {
+	removeVirtualEthOnHost(containerId)
+	// rootless containers mount only in their own mount namespace
+	if !isRootless() {
+		unmountNetWorkNamespace(containerId)
+		unmountContainerFS(containerId)
+	}
+	removeCGroups(containerId)
+}
 
-	imgConfig := parseContainerConfig(imageShaHex)
-	doOrDieWithMsg(unix.Sethostname([]byte(containerId)), "set container hostname failed")
-	doOrDieWithMsg(joinContainerNetworkNamespace(containerId), "join container Netns failed")
-	createCGroups(containerId, true)
-	configureCGroups(containerId, mem, swap, pids, cpus)
+func lookPathInContainer(file string, env []string) (string, error) {
+	if strings.Contains(file, "/") {
+		return file, nil
+	}
+	path, _ := getEnvValue(env, "PATH")
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		candidate := filepath.Join(dir, file)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s: executable file not found in $PATH", file)
+}
+
+func execContainerCommand(containerId string) {
+	mntPath := getContainerFSHome(containerId) + "/mnt"
+	config, err := loadContainerConfig(containerId)
+	doOrDieWithMsg(err, "load container config failed")
+	imgConfig := parseContainerConfig(config.ImageId)
+	// capabilities and the seccomp filter are set per thread, the workload is
+	// executed from this one
+	runtime.LockOSThread()
+	doOrDieWithMsg(waitForMappedSetup(), "wait for container setup failed")
+	doOrDieWithMsg(unix.Sethostname([]byte(config.Hostname)), "set container hostname failed")
+	if len(config.UidMappings) == 0 {
+		doOrDieWithMsg(joinContainerNetworkNamespace(containerId), "join container Netns failed")
+	}
+	doOrDieWithMsg(prepareRootPropagation(config.RootfsPropagation), "make root mount private failed")
+	if isRootless() {
+		// only the container user namespace may mount the overlay
+		mountOverlayFileSystem(containerId, config)
+	}
+	doOrDieWithMsg(bindRootfs(mntPath), "bind container root failed")
 	doOrDieWithMsg(copyNameserverConfig(containerId), "copy resolv.conf failed")
-	doOrDieWithMsg(unix.Chroot(mntPath), "change root failed")
-	doOrDieWithMsg(os.Chdir("/"), "change dir failed")
-	createDirsIfNotExist([]string{"/proc", "/sys"})
-	doOrDieWithMsg(unix.Mount("proc", "/proc", "proc", 0, ""), "mount proc failed")
-	doOrDieWithMsg(unix.Mount("tmpfs", "/tmp", "tmpfs", 0, ""), "mount tmpfs failed")
-	doOrDieWithMsg(unix.Mount("tmpfs", "/dev", "tmpfs", 0, ""), "mount tmpfs on /dev failed")
-	createDirsIfNotExist([]string{"/dev/pts"})
-	doOrDieWithMsg(unix.Mount("devpts", "/dev/pts", "devpts", 0, ""), "mount devpts failed")
-	doOrDieWithMsg(unix.Mount("sysfs", "/sys", "sysfs", 0, ""), "mount sysfs failed")
+	doOrDieWithMsg(writeHostnameConfig(containerId, config.Hostname), "write hostname failed")
+	doOrDieWithMsg(createWorkingDir(mntPath, imgConfig.Config.WorkingDir), "create working dir failed")
+	doOrDieWithMsg(mountContainerFileSystems(mntPath, config), "mount container file systems failed")
+	if config.ReadOnly {
+		doOrDieWithMsg(remountBind(mntPath, unix.MS_RDONLY), "remount root file system read-only failed")
+	}
+	var initExe *initExecutable
+	if config.Init {
+		initExe, err = openInitExecutable()
+		doOrDieWithMsg(err, "open container init failed")
+	}
+	doOrDieWithMsg(changeContainerRoot(mntPath, config.RootfsPropagation), "change root failed")
+	doOrDieWithMsg(writeSysctls(config.Sysctls), "set sysctls failed")
+	doOrDieWithMsg(applyPathMasks(config.MaskedPaths, config.ReadonlyPaths), "mask kernel paths failed")
 	setupLocalInterface()
-	cmd.Env = imgConfig.Config.Env
-	cmd.Run()
-	doOrDie(unix.Unmount("/dev/pts", 0))
-	doOrDie(unix.Unmount("/dev", 0))
-	doOrDie(unix.Unmount("/sys", 0))
-	doOrDie(unix.Unmount("/proc", 0))
-	doOrDie(unix.Unmount("/tmp", 0))
+	doOrDieWithMsg(changeToWorkingDir(imgConfig.Config.WorkingDir), "change to working dir failed")
+	user, err := resolveContainerUser(getContainerUserSpec(config.User, imgConfig))
+	doOrDieWithMsg(err, "resolve container user failed")
+	// the console and the files of an unmapped user would belong to no one
+	_, _, err = shiftOwner(config.UidMappings, config.GidMappings, user.Uid, user.Gid)
+	doOrDieWithMsg(err, "resolve container user failed")
+	env := addUserHomeEnv(buildContainerEnv(imgConfig.Config.Env, config.Hostname, config.Env), user)
+	path, err := lookPathInContainer(config.Args[0], env)
+	if err != nil {
+		log.Printf("find container command failed: %v\n", err)
+		os.Exit(exitCodeCommandNotFound)
+	}
+	if config.Tty {
+		doOrDieWithMsg(setupContainerConsole(os.NewFile(consoleSocketFd, "console-socket"), user.Uid), "set up container console failed")
+	}
+	doOrDieWithMsg(applyProcessSecurity(user, config.Capabilities, config.SeccompProfile, config.NoNewPrivileges, config.Ulimits, config.OOMScoreAdj), "drop container privileges failed")
+	if config.Init {
+		err = initExe.exec(initSpec{Path: path, Args: config.Args, Env: env})
+		log.Printf("exec container init failed: %v\n", err)
+		os.Exit(exitCodeCommandNotRunnable)
+	}
+	err = unix.Exec(path, config.Args, env)
+	log.Printf("exec container command failed: %v\n", err)
+	os.Exit(exitCodeCommandNotRunnable)
+}
+
+// waitForContainerSetup blocks until the supervisor has moved child-mode into
+// the container cgroups, the pipe is closed without data when it failed.
+func waitForContainerSetup() error {
+	syncPipe := os.NewFile(containerSyncFd, "sync-pipe")
+	defer syncPipe.Close()
+	buf := make([]byte, 1)
+	_, err := io.ReadFull(syncPipe, buf)
+	return err
+}
+
+func createWorkingDir(rootfs string, workingDir string) error {
+	if len(workingDir) == 0 {
+		return nil
+	}
+	path, err := secureJoin(rootfs, workingDir)
+	if err != nil {
+		return err
+	}
+	return createDirsIfNotExist([]string{path})
+}
+
+func changeToWorkingDir(workingDir string) error {
+	if len(workingDir) == 0 {
+		return nil
+	}
+	if err := createDirsIfNotExist([]string{workingDir}); err != nil {
+		return err
+	}
+	return os.Chdir(workingDir)
 }
 
 func copyNameserverConfig(containerId string) error {
@@ -158,20 +370,78 @@ func copyNameserverConfig(containerId string) error {
 	return nil
 }
 
-func initContainer(mem int, swap int, pids int, cpus float64, src string, args []string) {
+func writeHostnameConfig(containerId string, hostname string) error {
+	return os.WriteFile(getContainerFSHome(containerId)+"/mnt/etc/hostname", []byte(hostname+"\n"), 0644)
+}
+
+func initContainer(config containerConfig) int {
+	if len(config.Name) > 0 {
+		doOrDieWithMsg(validateContainerName(config.Name), "create container failed")
+	} else {
+		config.Name = createContainerName()
+	}
 	containerId := createContainerId()
-	log.Printf("container Id %s\n", containerId)
-	imageShaHex := downloadImageIfRequired(src)
+	if len(config.Hostname) == 0 {
+		config.Hostname = containerId
+	}
+	log.Printf("container Id %s, name %s\n", containerId, config.Name)
+	imageShaHex := downloadImageIfRequired(config.Image)
 	log.Printf("image to overlay mount %s\n", imageShaHex)
+	config.ImageId = imageShaHex
+	imgConfig := parseContainerConfig(imageShaHex)
+	config.Args = resolveContainerCommand(imgConfig, config.Entrypoint, config.Args)
+	if len(config.Args) == 0 {
+		log.Fatalln("no command specified")
+	}
+	mounts, err := createContainerVolumes(config.Mounts, imgConfig)
+	doOrDieWithMsg(err, "create container volumes failed")
+	config.Mounts = mounts
 	createContainerDirs(containerId)
-	mountOverlayFileSystem(containerId, imageShaHex)
-	if err := setupVirtualEthOnHost(containerId); err != nil {
-		log.Fatalln("set up veth0 failed")
+	doOrDieWithMsg(saveContainerConfig(containerId, config), "save container config failed")
+	imgName, tagName := getImageNameAndTag(config.Image)
+	doOrDieWithMsg(saveContainerState(containerState{
+		Id:            containerId,
+		Name:          config.Name,
+		Image:         imgName + ":" + tagName,
+		ImageId:       imageShaHex,
+		Command:       config.Args,
+		Status:        containerStatusCreated,
+		SupervisorPid: os.Getpid(),
+		CreatedAt:     time.Now(),
+	}), "save container state failed")
+	if config.Detach {
+		doOrDieWithMsg(startContainerShim(containerId), "start container shim failed")
+		fmt.Println(containerId)
+		return 0
+	}
+	exitCode := getExitCode(runContainer(containerId, config, true))
+	if config.AutoRemove {
+		removeContainerHome(containerId)
 	}
-	prepareAndExecuteContainer(mem, swap, pids, cpus, containerId, imageShaHex, args)
+	return exitCode
+}
+
+func runContainer(containerId string, config containerConfig, attach bool) error {
+	// a rootless container mounts its file system itself and has no veth
+	if !isRootless() {
+		mountOverlayFileSystem(containerId, config)
+		if len(config.UidMappings) > 0 {
+			doOrDieWithMsg(shiftVolumeOwners(config.Mounts, config.UidMappings, config.GidMappings), "change volume owner failed")
+		}
+		if err := setupVirtualEthOnHost(containerId); err != nil {
+			log.Fatalln("set up veth0 failed")
+		}
+	}
+	err := prepareAndExecuteContainer(containerId, config, attach)
 	log.Println("container done")
-	unmountNetWorkNamespace(containerId)
-	unmountContainerFS(containerId)
-	removeCGroups(containerId)
-	os.RemoveAll(getGockerContainersPath() + "/" + containerId)
+	if err := updateContainerState(containerId, func(state *containerState) {
+		state.Pid = 0
+		state.Status = containerStatusExited
+		state.ExitCode = getExitCode(err)
+		state.FinishedAt = time.Now()
+	}); err != nil {
+		log.Printf("update container state failed: %v\n", err)
+	}
+	cleanupContainer(containerId)
+	return err
 }
@@ -0,0 +1,567 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+const seccompUnconfined = "unconfined"
+
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetKillThread  = 0x00000000
+	seccompRetTrap        = 0x00030000
+	seccompRetErrno       = 0x00050000
+	seccompRetTrace       = 0x7ff00000
+	seccompRetLog         = 0x7ffc0000
+	seccompRetAllow       = 0x7fff0000
+	seccompRetDataMask    = 0x0000ffff
+)
+
+// offsets into struct seccomp_data
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArgs = 16
+)
+
+// bpfMaxInsns is the largest filter the kernel accepts
+const bpfMaxInsns = 4096
+
+//go:embed seccomp_default.json
+var defaultSeccompProfile string
+
+// unifiedSyscallNumbers are system calls newer than the tables of
+// golang.org/x/sys/unix, from 424 on every architecture shares the numbers.
+var unifiedSyscallNumbers = map[string]int{
+	"cachestat":         451,
+	"fchmodat2":         452,
+	"map_shadow_stack":  453,
+	"futex_wake":        454,
+	"futex_wait":        455,
+	"futex_requeue":     456,
+	"statmount":         457,
+	"listmount":         458,
+	"lsm_get_self_attr": 459,
+	"lsm_set_self_attr": 460,
+	"lsm_list_modules":  461,
+	"mseal":             462,
+}
+
+type seccompArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+type seccompFilter struct {
+	Caps      []string `json:"caps"`
+	Arches    []string `json:"arches"`
+	MinKernel string   `json:"minKernel"`
+}
+
+type seccompSyscall struct {
+	Name     string        `json:"name"`
+	Names    []string      `json:"names"`
+	Action   string        `json:"action"`
+	ErrnoRet *uint         `json:"errnoRet"`
+	Args     []seccompArg  `json:"args"`
+	Includes seccompFilter `json:"includes"`
+	Excludes seccompFilter `json:"excludes"`
+}
+
+type seccompArchMap struct {
+	Architecture     string   `json:"architecture"`
+	SubArchitectures []string `json:"subArchitectures"`
+}
+
+type seccompProfile struct {
+	DefaultAction   string           `json:"defaultAction"`
+	DefaultErrnoRet *uint            `json:"defaultErrnoRet"`
+	Architectures   []string         `json:"architectures"`
+	ArchMap         []seccompArchMap `json:"archMap"`
+	Syscalls        []seccompSyscall `json:"syscalls"`
+}
+
+// seccompSubArch is an architecture whose system calls the native one also
+// runs, such as 32-bit x86 on amd64. Profiles turn them on with archMap.
+type seccompSubArch struct {
+	name     string
+	token    uint32
+	nrBits   uint32
+	syscalls map[string]int
+}
+
+// seccompRule is a profile rule that applies to the container, with the
+// action it takes
+type seccompRule struct {
+	names  []string
+	action uint32
+	args   []seccompArg
+}
+
+// readSeccompProfile returns the profile to store in the container config for
+// the value of --security-opt seccomp=
+func readSeccompProfile(value string) (string, error) {
+	if value == seccompUnconfined {
+		return value, nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func parseSeccompProfile(data string) (seccompProfile, error) {
+	profile := seccompProfile{}
+	if err := json.Unmarshal([]byte(data), &profile); err != nil {
+		return profile, fmt.Errorf("decode seccomp profile failed: %v", err)
+	}
+	return profile, nil
+}
+
+func getSeccompAction(action string, errnoRet *uint) (uint32, error) {
+	data := uint32(unix.EPERM)
+	if errnoRet != nil {
+		data = uint32(*errnoRet) & seccompRetDataMask
+	}
+	switch action {
+	case "SCMP_ACT_KILL", "SCMP_ACT_KILL_THREAD":
+		return seccompRetKillThread, nil
+	case "SCMP_ACT_KILL_PROCESS":
+		return seccompRetKillProcess, nil
+	case "SCMP_ACT_TRAP":
+		return seccompRetTrap, nil
+	case "SCMP_ACT_ERRNO":
+		return seccompRetErrno | data, nil
+	case "SCMP_ACT_TRACE":
+		if errnoRet == nil {
+			data = 0
+		}
+		return seccompRetTrace | data, nil
+	case "SCMP_ACT_LOG":
+		return seccompRetLog, nil
+	case "SCMP_ACT_ALLOW":
+		return seccompRetAllow, nil
+	}
+	return 0, fmt.Errorf("unsupported seccomp action: %s", action)
+}
+
+func getSyscallNumber(syscalls map[string]int, name string) (int, bool) {
+	if num, ok := syscalls[name]; ok {
+		return num, true
+	}
+	num, ok := unifiedSyscallNumbers[name]
+	return num, ok
+}
+
+func getKernelVersion() (int, int, error) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return 0, 0, err
+	}
+	return parseKernelVersion(unix.ByteSliceToString(uts.Release[:]))
+}
+
+func parseKernelVersion(release string) (int, int, error) {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid kernel version: %s", release)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid kernel version: %s", release)
+	}
+	minor, err := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid kernel version: %s", release)
+	}
+	return major, minor, nil
+}
+
+func isKernelAtLeast(version string) (bool, error) {
+	wantMajor, wantMinor, err := parseKernelVersion(version)
+	if err != nil {
+		return false, err
+	}
+	major, minor, err := getKernelVersion()
+	if err != nil {
+		return false, err
+	}
+	return major > wantMajor || (major == wantMajor && minor >= wantMinor), nil
+}
+
+func hasAnyString(list []string, targets []string) bool {
+	for _, target := range targets {
+		if stringInSlice(target, list) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeccompRuleApplicable evaluates the includes and excludes of a rule
+// against the container capabilities and the native architecture.
+func isSeccompRuleApplicable(rule seccompSyscall, caps []string) (bool, error) {
+	for _, name := range rule.Includes.Caps {
+		if !stringInSlice(name, caps) {
+			return false, nil
+		}
+	}
+	if len(rule.Includes.Arches) > 0 && !hasAnyString(rule.Includes.Arches, seccompArchNames) {
+		return false, nil
+	}
+	if len(rule.Includes.MinKernel) > 0 {
+		ok, err := isKernelAtLeast(rule.Includes.MinKernel)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if hasAnyString(caps, rule.Excludes.Caps) || hasAnyString(rule.Excludes.Arches, seccompArchNames) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// bpfInsn is a classic BPF instruction whose jumps still refer to labels,
+// label 0 is the next instruction. Unconditional jumps keep theirs in ja.
+type bpfInsn struct {
+	code   uint16
+	k      uint32
+	jt, jf int
+	ja     int
+}
+
+type bpfAssembler struct {
+	insns  []bpfInsn
+	labels []int
+}
+
+func newBpfAssembler() *bpfAssembler {
+	return &bpfAssembler{labels: []int{-1}}
+}
+
+func (a *bpfAssembler) newLabel() int {
+	a.labels = append(a.labels, -1)
+	return len(a.labels) - 1
+}
+
+func (a *bpfAssembler) bind(label int) {
+	a.labels[label] = len(a.insns)
+}
+
+func (a *bpfAssembler) stmt(code uint16, k uint32) {
+	a.insns = append(a.insns, bpfInsn{code: code, k: k})
+}
+
+func (a *bpfAssembler) jump(code uint16, k uint32, jt int, jf int) {
+	a.insns = append(a.insns, bpfInsn{code: code, k: k, jt: jt, jf: jf})
+}
+
+// jumpAlways jumps to label, which unlike the conditional jumps may be more
+// than 255 instructions away
+func (a *bpfAssembler) jumpAlways(label int) {
+	a.insns = append(a.insns, bpfInsn{code: unix.BPF_JMP | unix.BPF_JA, ja: label})
+}
+
+func (a *bpfAssembler) labelOffset(from int, label int) (int, error) {
+	if label == 0 {
+		return 0, nil
+	}
+	to := a.labels[label]
+	if to < 0 {
+		return 0, fmt.Errorf("unbound bpf label %d", label)
+	}
+	if to <= from {
+		return 0, fmt.Errorf("bpf jump backwards to label %d", label)
+	}
+	return to - from - 1, nil
+}
+
+func (a *bpfAssembler) offset(from int, label int) (uint8, error) {
+	offset, err := a.labelOffset(from, label)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 255 {
+		return 0, fmt.Errorf("bpf jump out of range: %d", offset)
+	}
+	return uint8(offset), nil
+}
+
+func (a *bpfAssembler) assemble() ([]unix.SockFilter, error) {
+	if len(a.insns) > bpfMaxInsns {
+		return nil, fmt.Errorf("seccomp filter has %d instructions, the limit is %d", len(a.insns), bpfMaxInsns)
+	}
+	filter := make([]unix.SockFilter, len(a.insns))
+	for i, insn := range a.insns {
+		jt, err := a.offset(i, insn.jt)
+		if err != nil {
+			return nil, err
+		}
+		jf, err := a.offset(i, insn.jf)
+		if err != nil {
+			return nil, err
+		}
+		k := insn.k
+		if insn.ja != 0 {
+			offset, err := a.labelOffset(i, insn.ja)
+			if err != nil {
+				return nil, err
+			}
+			k = uint32(offset)
+		}
+		filter[i] = unix.SockFilter{Code: insn.code, Jt: jt, Jf: jf, K: k}
+	}
+	return filter, nil
+}
+
+func (a *bpfAssembler) loadWord(offset uint32) {
+	a.stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offset)
+}
+
+func (a *bpfAssembler) ret(k uint32) {
+	a.stmt(unix.BPF_RET|unix.BPF_K, k)
+}
+
+// loadArgHalf loads the upper or lower 32 bits of a system call argument
+func (a *bpfAssembler) loadArgHalf(index uint, upper bool) {
+	offset := uint32(seccompDataArgs + 8*index)
+	if upper != seccompBigEndian {
+		offset += 4
+	}
+	a.loadWord(offset)
+}
+
+// compileSeccompArg emits a 64-bit comparison of one argument, continuing
+// with the next instruction when it holds and jumping to fail otherwise.
+func (a *bpfAssembler) compileSeccompArg(arg seccompArg, fail int) error {
+	if arg.Index > 5 {
+		return fmt.Errorf("invalid seccomp argument index: %d", arg.Index)
+	}
+	pass := a.newLabel()
+	valueHigh, valueLow := uint32(arg.Value>>32), uint32(arg.Value)
+	const (
+		jeq = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		jgt = unix.BPF_JMP | unix.BPF_JGT | unix.BPF_K
+		jge = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+	)
+	switch arg.Op {
+	case "SCMP_CMP_EQ":
+		a.loadArgHalf(arg.Index, true)
+		a.jump(jeq, valueHigh, 0, fail)
+		a.loadArgHalf(arg.Index, false)
+		a.jump(jeq, valueLow, 0, fail)
+	case "SCMP_CMP_NE":
+		a.loadArgHalf(arg.Index, true)
+		a.jump(jeq, valueHigh, 0, pass)
+		a.loadArgHalf(arg.Index, false)
+		a.jump(jeq, valueLow, fail, 0)
+	case "SCMP_CMP_MASKED_EQ":
+		datumHigh, datumLow := uint32(arg.ValueTwo>>32), uint32(arg.ValueTwo)
+		a.loadArgHalf(arg.Index, true)
+		a.stmt(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, valueHigh)
+		a.jump(jeq, datumHigh, 0, fail)
+		a.loadArgHalf(arg.Index, false)
+		a.stmt(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, valueLow)
+		a.jump(jeq, datumLow, 0, fail)
+	case "SCMP_CMP_GT", "SCMP_CMP_GE":
+		a.loadArgHalf(arg.Index, true)
+		a.jump(jgt, valueHigh, pass, 0)
+		a.jump(jeq, valueHigh, 0, fail)
+		a.loadArgHalf(arg.Index, false)
+		if arg.Op == "SCMP_CMP_GT" {
+			a.jump(jgt, valueLow, 0, fail)
+		} else {
+			a.jump(jge, valueLow, 0, fail)
+		}
+	case "SCMP_CMP_LT", "SCMP_CMP_LE":
+		a.loadArgHalf(arg.Index, true)
+		a.jump(jgt, valueHigh, fail, 0)
+		a.jump(jeq, valueHigh, 0, pass)
+		a.loadArgHalf(arg.Index, false)
+		if arg.Op == "SCMP_CMP_LT" {
+			a.jump(jge, valueLow, fail, 0)
+		} else {
+			a.jump(jgt, valueLow, fail, 0)
+		}
+	default:
+		return fmt.Errorf("unsupported seccomp operator: %s", arg.Op)
+	}
+	a.bind(pass)
+	return nil
+}
+
+// getSeccompSubArches returns the subarchitectures the profile allows next to
+// the native architecture, through archMap or the older architectures list.
+func getSeccompSubArches(profile seccompProfile) []seccompSubArch {
+	var subArches []seccompSubArch
+	for _, subArch := range seccompSubArches {
+		enabled := stringInSlice(subArch.name, profile.Architectures)
+		for _, archMap := range profile.ArchMap {
+			if stringInSlice(archMap.Architecture, seccompArchNames) && stringInSlice(subArch.name, archMap.SubArchitectures) {
+				enabled = true
+			}
+		}
+		if enabled {
+			subArches = append(subArches, subArch)
+		}
+	}
+	return subArches
+}
+
+// getSeccompRules returns the rules of the profile that apply to the
+// container. Like docker, includes and excludes are matched against the
+// native architecture and a rule covers every architecture of the filter.
+func getSeccompRules(profile seccompProfile, caps []string) ([]seccompRule, error) {
+	var rules []seccompRule
+	for _, rule := range profile.Syscalls {
+		ok, err := isSeccompRuleApplicable(rule, caps)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		action, err := getSeccompAction(rule.Action, rule.ErrnoRet)
+		if err != nil {
+			return nil, err
+		}
+		names := rule.Names
+		if len(rule.Name) > 0 {
+			names = append(names, rule.Name)
+		}
+		rules = append(rules, seccompRule{names: names, action: action, args: rule.Args})
+	}
+	return rules, nil
+}
+
+// compileSeccompRules emits the rules for the architecture whose system call
+// numbers are given, the number must be loaded. It ends with the default action.
+func (a *bpfAssembler) compileSeccompRules(rules []seccompRule, syscalls map[string]int, nrBits uint32, defaultAction uint32) error {
+	for _, rule := range rules {
+		for _, name := range rule.names {
+			// like libseccomp, names unknown to this architecture are skipped
+			num, ok := getSyscallNumber(syscalls, name)
+			if !ok {
+				continue
+			}
+			next := a.newLabel()
+			a.jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(num)|nrBits, 0, next)
+			for _, arg := range rule.args {
+				if err := a.compileSeccompArg(arg, next); err != nil {
+					return err
+				}
+			}
+			a.ret(rule.action)
+			a.bind(next)
+			if len(rule.args) > 0 {
+				a.loadWord(seccompDataNr)
+			}
+		}
+	}
+	a.ret(defaultAction)
+	return nil
+}
+
+// compileSeccompProfile turns a profile in docker's JSON format into a BPF
+// program. Rules are checked in profile order and the first match decides.
+// System calls from architectures that are neither native nor allowed as a
+// subarchitecture by the profile kill the process.
+func compileSeccompProfile(data string, caps []string) ([]unix.SockFilter, error) {
+	if seccompNativeArch == 0 {
+		return nil, fmt.Errorf("seccomp is not supported on this architecture")
+	}
+	profile, err := parseSeccompProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	defaultAction, err := getSeccompAction(profile.DefaultAction, profile.DefaultErrnoRet)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := getSeccompRules(profile, caps)
+	if err != nil {
+		return nil, err
+	}
+	subArches := getSeccompSubArches(profile)
+	subArchLabels := make([]int, len(subArches))
+	// x32 shares the native architecture and is told apart by seccompSyscallBadBits
+	badBitsLabel := 0
+
+	a := newBpfAssembler()
+	nativeArch := a.newLabel()
+	a.loadWord(seccompDataArch)
+	a.jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, seccompNativeArch, nativeArch, 0)
+	for i, subArch := range subArches {
+		subArchLabels[i] = a.newLabel()
+		if subArch.token == seccompNativeArch {
+			badBitsLabel = subArchLabels[i]
+			continue
+		}
+		next := a.newLabel()
+		a.jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, subArch.token, 0, next)
+		a.jumpAlways(subArchLabels[i])
+		a.bind(next)
+	}
+	a.ret(seccompRetKillProcess)
+	a.bind(nativeArch)
+	a.loadWord(seccompDataNr)
+	if seccompSyscallBadBits != 0 {
+		goodNr := a.newLabel()
+		a.jump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, seccompSyscallBadBits, 0, goodNr)
+		if badBitsLabel != 0 {
+			a.jumpAlways(badBitsLabel)
+		} else {
+			a.ret(seccompRetKillProcess)
+		}
+		a.bind(goodNr)
+	}
+	if err := a.compileSeccompRules(rules, syscallNumbers, 0, defaultAction); err != nil {
+		return nil, err
+	}
+	for i, subArch := range subArches {
+		a.bind(subArchLabels[i])
+		a.loadWord(seccompDataNr)
+		if err := a.compileSeccompRules(rules, subArch.syscalls, subArch.nrBits, defaultAction); err != nil {
+			return nil, err
+		}
+	}
+	return a.assemble()
+}
+
+// loadSeccompFilter installs filter on the calling thread, which must stay
+// locked until it executes the container command. The thread needs
+// no_new_privs or CAP_SYS_ADMIN to install a filter.
+func loadSeccompFilter(filter []unix.SockFilter) error {
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	return unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0)
+}
+
+// getSeccompFilter compiles the profile stored in the container config, empty
+// means the built-in default profile and unconfined gives no filter.
+func getSeccompFilter(profile string, caps []string) ([]unix.SockFilter, error) {
+	if profile == seccompUnconfined {
+		return nil, nil
+	}
+	if len(profile) == 0 {
+		profile = defaultSeccompProfile
+	}
+	return compileSeccompProfile(profile, caps)
+}
+
+func applySeccompProfile(profile string, caps []string) error {
+	filter, err := getSeccompFilter(profile, caps)
+	if err != nil || filter == nil {
+		return err
+	}
+	return loadSeccompFilter(filter)
+}
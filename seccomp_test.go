@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const seccompHelperEnv = "GOCKER_TEST_SECCOMP_HELPER"
+
+// runSeccompFilter interprets the classic BPF instructions the compiler emits
+// against a seccomp_data built from arch, nr and args, and returns the action.
+func runSeccompFilter(t *testing.T, filter []unix.SockFilter, arch uint32, nr uint32, args [6]uint64) uint32 {
+	t.Helper()
+	var order binary.ByteOrder = binary.LittleEndian
+	if seccompBigEndian {
+		order = binary.BigEndian
+	}
+	data := make([]byte, seccompDataArgs+8*len(args))
+	order.PutUint32(data[seccompDataNr:], nr)
+	order.PutUint32(data[seccompDataArch:], arch)
+	for i, arg := range args {
+		order.PutUint64(data[seccompDataArgs+8*i:], arg)
+	}
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		insn := filter[pc]
+		jump := func(cond bool) {
+			if cond {
+				pc += int(insn.Jt)
+			} else {
+				pc += int(insn.Jf)
+			}
+		}
+		switch insn.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			acc = order.Uint32(data[insn.K:])
+		case unix.BPF_ALU | unix.BPF_AND | unix.BPF_K:
+			acc &= insn.K
+		case unix.BPF_JMP | unix.BPF_JA:
+			pc += int(insn.K)
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			jump(acc == insn.K)
+		case unix.BPF_JMP | unix.BPF_JGT | unix.BPF_K:
+			jump(acc > insn.K)
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			jump(acc >= insn.K)
+		case unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K:
+			jump(acc&insn.K != 0)
+		case unix.BPF_RET | unix.BPF_K:
+			return insn.K
+		default:
+			t.Fatalf("unexpected bpf instruction %#x at %d", insn.Code, pc)
+		}
+	}
+	t.Fatal("seccomp filter ended without returning")
+	return 0
+}
+
+func compileTestProfile(t *testing.T, profile seccompProfile) []unix.SockFilter {
+	t.Helper()
+	data, err := json.Marshal(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := compileSeccompProfile(string(data), nil)
+	if err != nil {
+		t.Fatalf("compile seccomp profile failed: %v", err)
+	}
+	return filter
+}
+
+func TestSeccompArgOperators(t *testing.T) {
+	if seccompNativeArch == 0 {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	values := []uint64{0, 1, 0x7fffffff, 0xfffffffe, 0xffffffff, 0x100000000, 0x100000001, 0x1fffffffe, 0xffffffff00000000, 0xffffffffffffffff}
+	ops := []struct {
+		op    string
+		holds func(arg, value uint64) bool
+	}{
+		{"SCMP_CMP_EQ", func(arg, value uint64) bool { return arg == value }},
+		{"SCMP_CMP_NE", func(arg, value uint64) bool { return arg != value }},
+		{"SCMP_CMP_GT", func(arg, value uint64) bool { return arg > value }},
+		{"SCMP_CMP_GE", func(arg, value uint64) bool { return arg >= value }},
+		{"SCMP_CMP_LT", func(arg, value uint64) bool { return arg < value }},
+		{"SCMP_CMP_LE", func(arg, value uint64) bool { return arg <= value }},
+	}
+	nr, _ := getSyscallNumber(syscallNumbers, "getpid")
+	for _, op := range ops {
+		for _, value := range values {
+			filter := compileTestProfile(t, seccompProfile{
+				DefaultAction: "SCMP_ACT_ALLOW",
+				Syscalls: []seccompSyscall{{
+					Names:  []string{"getpid"},
+					Action: "SCMP_ACT_KILL_PROCESS",
+					Args:   []seccompArg{{Index: 2, Value: value, Op: op.op}},
+				}},
+			})
+			for _, arg := range values {
+				want := uint32(seccompRetAllow)
+				if op.holds(arg, value) {
+					want = seccompRetKillProcess
+				}
+				got := runSeccompFilter(t, filter, seccompNativeArch, uint32(nr), [6]uint64{2: arg})
+				if got != want {
+					t.Errorf("%s %#x with argument %#x returned %#x, want %#x", op.op, value, arg, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSeccompArgMaskedEq(t *testing.T) {
+	if seccompNativeArch == 0 {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	tests := []struct {
+		mask, datum, arg uint64
+		holds            bool
+	}{
+		{0xffffffff, 0x80000000, 0x80000000, true},
+		{0xffffffff, 0x80000000, 0x180000000, true},
+		{0x1ffffffff, 0x80000000, 0x180000000, false},
+		{0x100000000, 0x100000000, 0x1ffffffff, true},
+		{0x100000000, 0x100000000, 0xffffffff, false},
+		{0xffffffff00000000, 0, 0xffffffff, true},
+		{0xffffffff00000000, 0, 0x100000000, false},
+		{0xffffffffffffffff, 0xffffffffffffffff, 0xffffffffffffffff, true},
+		{0x7e000000, 0, 0x2000000, false},
+	}
+	nr, _ := getSyscallNumber(syscallNumbers, "getpid")
+	for _, test := range tests {
+		filter := compileTestProfile(t, seccompProfile{
+			DefaultAction: "SCMP_ACT_ALLOW",
+			Syscalls: []seccompSyscall{{
+				Names:  []string{"getpid"},
+				Action: "SCMP_ACT_KILL_PROCESS",
+				Args:   []seccompArg{{Index: 0, Value: test.mask, ValueTwo: test.datum, Op: "SCMP_CMP_MASKED_EQ"}},
+			}},
+		})
+		want := uint32(seccompRetAllow)
+		if test.holds {
+			want = seccompRetKillProcess
+		}
+		if got := runSeccompFilter(t, filter, seccompNativeArch, uint32(nr), [6]uint64{test.arg}); got != want {
+			t.Errorf("mask %#x datum %#x with argument %#x returned %#x, want %#x", test.mask, test.datum, test.arg, got, want)
+		}
+	}
+}
+
+func TestSeccompSubArches(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("the subarchitecture numbers below are the amd64 ones")
+	}
+	filter, err := getSeccompFilter("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eperm := uint32(seccompRetErrno | uint32(unix.EPERM))
+	tests := []struct {
+		name string
+		arch uint32
+		nr   uint32
+		want uint32
+	}{
+		{"x86_64 getpid", unix.AUDIT_ARCH_X86_64, unix.SYS_GETPID, seccompRetAllow},
+		{"x86_64 keyctl", unix.AUDIT_ARCH_X86_64, unix.SYS_KEYCTL, eperm},
+		{"i386 getpid", unix.AUDIT_ARCH_I386, 20, seccompRetAllow},
+		{"i386 keyctl", unix.AUDIT_ARCH_I386, 288, eperm},
+		{"x32 getpid", unix.AUDIT_ARCH_X86_64, seccompSyscallBadBits | unix.SYS_GETPID, seccompRetAllow},
+		{"x32 execve", unix.AUDIT_ARCH_X86_64, seccompSyscallBadBits | 520, seccompRetAllow},
+		{"x32 keyctl", unix.AUDIT_ARCH_X86_64, seccompSyscallBadBits | unix.SYS_KEYCTL, eperm},
+		{"arm getpid", unix.AUDIT_ARCH_ARM, 20, seccompRetKillProcess},
+	}
+	for _, test := range tests {
+		if got := runSeccompFilter(t, filter, test.arch, test.nr, [6]uint64{}); got != test.want {
+			t.Errorf("%s returned %#x, want %#x", test.name, got, test.want)
+		}
+	}
+
+	// without archMap only the native architecture is allowed
+	filter = compileTestProfile(t, seccompProfile{DefaultAction: "SCMP_ACT_ALLOW"})
+	if got := runSeccompFilter(t, filter, unix.AUDIT_ARCH_I386, 20, [6]uint64{}); got != seccompRetKillProcess {
+		t.Errorf("i386 getpid without archMap returned %#x, want %#x", got, uint32(seccompRetKillProcess))
+	}
+	if got := runSeccompFilter(t, filter, unix.AUDIT_ARCH_X86_64, seccompSyscallBadBits|unix.SYS_GETPID, [6]uint64{}); got != seccompRetKillProcess {
+		t.Errorf("x32 getpid without archMap returned %#x, want %#x", got, uint32(seccompRetKillProcess))
+	}
+}
+
+// seccompHelperCalls are the system calls the helper process makes under the
+// default profile, along with the errno each should return
+var seccompHelperCalls = []struct {
+	name  string
+	args  [3]uintptr
+	errno unix.Errno
+}{
+	{"getpid", [3]uintptr{}, 0},
+	{"personality", [3]uintptr{0x1234}, unix.EPERM},
+	{"personality", [3]uintptr{8}, 0},
+	{"keyctl", [3]uintptr{}, unix.EPERM},
+	{"unshare", [3]uintptr{unix.CLONE_NEWUTS}, unix.EPERM},
+	{"socket", [3]uintptr{unix.AF_VSOCK, unix.SOCK_STREAM}, unix.EPERM},
+	{"socket", [3]uintptr{unix.AF_INET, unix.SOCK_STREAM}, 0},
+	{"clone3", [3]uintptr{}, unix.ENOSYS},
+}
+
+// TestSeccompHelperProcess is run by TestDefaultSeccompProfile in a process
+// of its own, since a loaded filter can not be removed again.
+func TestSeccompHelperProcess(t *testing.T) {
+	if os.Getenv(seccompHelperEnv) != "1" {
+		return
+	}
+	runtime.LockOSThread()
+	caps, err := resolveCapabilities(nil, nil, false)
+	if err != nil {
+		fmt.Println("load", err)
+		os.Exit(1)
+	}
+	filter, err := getSeccompFilter("", caps)
+	if err != nil {
+		fmt.Println("load", err)
+		os.Exit(1)
+	}
+	if err := setNoNewPrivileges(); err != nil {
+		fmt.Println("load", err)
+		os.Exit(1)
+	}
+	if err := loadSeccompFilter(filter); err != nil {
+		fmt.Println("load", err)
+		os.Exit(1)
+	}
+	for i, call := range seccompHelperCalls {
+		nr, _ := getSyscallNumber(syscallNumbers, call.name)
+		r, _, errno := unix.Syscall(uintptr(nr), call.args[0], call.args[1], call.args[2])
+		if call.name == "socket" && errno == 0 {
+			unix.Close(int(r))
+		}
+		fmt.Println(i, int(errno))
+	}
+}
+
+func TestDefaultSeccompProfile(t *testing.T) {
+	if seccompNativeArch == 0 {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSeccompHelperProcess$")
+	cmd.Env = append(os.Environ(), seccompHelperEnv+"=1")
+	out, err := cmd.Output()
+	if strings.HasPrefix(string(out), "load ") {
+		t.Skipf("load seccomp filter failed: %s", strings.TrimPrefix(strings.TrimSpace(string(out)), "load "))
+	}
+	if err != nil {
+		t.Fatalf("seccomp helper failed: %v: %s", err, out)
+	}
+	results := map[int]unix.Errno{}
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		i, iErr := strconv.Atoi(fields[0])
+		errno, errnoErr := strconv.Atoi(fields[1])
+		if iErr != nil || errnoErr != nil {
+			continue
+		}
+		results[i] = unix.Errno(errno)
+	}
+	for i, call := range seccompHelperCalls {
+		errno, ok := results[i]
+		if !ok {
+			t.Errorf("%s%v did not report a result", call.name, call.args)
+			continue
+		}
+		if errno != call.errno {
+			t.Errorf("%s%v returned %v, want %v", call.name, call.args, errno, call.errno)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"strconv"
+	"strings"
+)
+
+type securityOptions struct {
+	SeccompProfile  string
+	Unmask          []string
+	NoNewPrivileges bool
+}
+
+// parseSecurityOptions parses the key=value pairs given with --security-opt,
+// no-new-privileges is on unless it is turned off
+func parseSecurityOptions(opts []string) (securityOptions, error) {
+	options := securityOptions{NoNewPrivileges: true}
+	for _, opt := range opts {
+		key, value, ok := strings.Cut(opt, "=")
+		if opt == "no-new-privileges" {
+			value, ok = "true", true
+		}
+		if !ok {
+			return options, fmt.Errorf("invalid security option: %s", opt)
+		}
+		switch key {
+		case "seccomp":
+			profile, err := readSeccompProfile(value)
+			if err != nil {
+				return options, fmt.Errorf("read seccomp profile failed: %v", err)
+			}
+			options.SeccompProfile = profile
+		case "unmask":
+			paths, err := parseUnmaskOption(value)
+			if err != nil {
+				return options, err
+			}
+			options.Unmask = append(options.Unmask, paths...)
+		case "no-new-privileges":
+			noNewPrivileges, err := strconv.ParseBool(value)
+			if err != nil {
+				return options, fmt.Errorf("invalid no-new-privileges value: %s", value)
+			}
+			options.NoNewPrivileges = noNewPrivileges
+		default:
+			return options, fmt.Errorf("unknown security option: %s", key)
+		}
+	}
+	return options, nil
+}
+
+// setNoNewPrivileges keeps execve from granting privileges, through setuid
+// binaries or file capabilities, to the calling thread and its children.
+func setNoNewPrivileges() error {
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
+
+// applyProcessSecurity drops the calling thread to the container user with
+// the given limits, capabilities and seccomp profile, right before it execs
+// the container process. The run and exec paths both go through it.
+func applyProcessSecurity(user containerUser, caps []string, profile string, noNewPrivileges bool, ulimits []ulimit, oomScoreAdj *int) error {
+	if err := applyProcessLimits(ulimits, oomScoreAdj); err != nil {
+		return fmt.Errorf("apply process limits failed: %v", err)
+	}
+	if !noNewPrivileges {
+		// without no_new_privs the filter needs CAP_SYS_ADMIN, which is dropped next
+		if err := applySeccompProfile(profile, caps); err != nil {
+			return fmt.Errorf("apply seccomp profile failed: %v", err)
+		}
+	}
+	if err := limitCapabilities(caps, true); err != nil {
+		return fmt.Errorf("drop capabilities failed: %v", err)
+	}
+	if err := switchContainerUser(user); err != nil {
+		return fmt.Errorf("switch container user failed: %v", err)
+	}
+	if err := applyCapabilities(caps); err != nil {
+		return fmt.Errorf("set capabilities failed: %v", err)
+	}
+	if !noNewPrivileges {
+		return nil
+	}
+	if err := setNoNewPrivileges(); err != nil {
+		return fmt.Errorf("set no_new_privs failed: %v", err)
+	}
+	if err := applySeccompProfile(profile, caps); err != nil {
+		return fmt.Errorf("apply seccomp profile failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"golang.org/x/sys/unix"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+)
+
+// shimSyncFd is the descriptor the shim waits on until its pid is recorded as
+// the container supervisor
+const shimSyncFd = 3
+
+func startContainerShim(containerId string) error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer devNull.Close()
+	logFile, err := os.Create(getContainerHome(containerId) + "/shim.log")
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	syncReader, syncWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer syncWriter.Close()
+
+	cmd := exec.Command("/proc/self/exe", "shim", containerId)
+	cmd.Stdin = devNull
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.ExtraFiles = []*os.File{syncReader}
+	cmd.SysProcAttr = &unix.SysProcAttr{
+		Setsid: true,
+	}
+	err = cmd.Start()
+	syncReader.Close()
+	if err != nil {
+		return err
+	}
+	// the shim takes over before this process exits, so there is always a
+	// supervisor to tell whether a created container is still starting
+	if err := updateContainerState(containerId, func(state *containerState) {
+		state.SupervisorPid = cmd.Process.Pid
+	}); err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+	if _, err := syncWriter.Write([]byte{0}); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+func runContainerShim(containerId string) {
+	syncPipe := os.NewFile(shimSyncFd, "sync-pipe")
+	_, err := io.ReadFull(syncPipe, make([]byte, 1))
+	syncPipe.Close()
+	doOrDieWithMsg(err, "wait for container shim release failed")
+	config, err := loadContainerConfig(containerId)
+	doOrDieWithMsg(err, "load container config failed")
+	exitCode := getExitCode(runContainer(containerId, config, false))
+	log.Printf("container exited with code %d\n", exitCode)
+	if config.AutoRemove {
+		removeContainerHome(containerId)
+	}
+}
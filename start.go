@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func startContainer(containerId string, attach bool) int {
+	container, err := loadContainerState(containerId)
+	if err != nil {
+		log.Fatalln("no such container")
+	}
+	if isContainerRunning(container) {
+		log.Fatalf("container %s is already running\n", containerId)
+	}
+	if isContainerSupervised(container) {
+		// its supervisor is still starting it or has yet to clean it up
+		log.Fatalf("container %s is still being started or stopped\n", containerId)
+	}
+	config, err := loadContainerConfig(containerId)
+	doOrDieWithMsg(err, "load container config failed")
+	cleanupContainer(containerId)
+	doOrDieWithMsg(updateContainerState(containerId, func(state *containerState) {
+		state.Pid = 0
+		state.Status = containerStatusCreated
+		state.SupervisorPid = os.Getpid()
+		state.ExitCode = 0
+		state.FinishedAt = time.Time{}
+	}), "update container state failed")
+
+	if !attach {
+		doOrDieWithMsg(startContainerShim(containerId), "start container shim failed")
+		fmt.Println(containerId)
+		return 0
+	}
+	exitCode := getExitCode(runContainer(containerId, config, true))
+	if config.AutoRemove {
+		removeContainerHome(containerId)
+	}
+	return exitCode
+}
+
+// waitContainer blocks until a container exits and returns its exit code, or
+// gives up on a created container whose supervisor is gone
+func waitContainer(containerId string) int {
+	for {
+		container, err := loadContainerState(containerId)
+		if err != nil {
+			log.Fatalln("no such container")
+		}
+		if !isContainerSupervised(container) && !isContainerRunning(container) {
+			if container.Status == containerStatusCreated {
+				log.Fatalf("container %s was never started, its supervisor is gone\n", containerId)
+			}
+			return container.ExitCode
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"log"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func parseSignal(sig string) (syscall.Signal, error) {
+	if num, err := strconv.Atoi(sig); err == nil {
+		return syscall.Signal(num), nil
+	}
+	name := strings.ToUpper(sig)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if signal := unix.SignalNum(name); signal != 0 {
+		return signal, nil
+	}
+	return 0, fmt.Errorf("invalid signal: %s", sig)
+}
+
+func getStopSignal(container containerState) syscall.Signal {
+	imgConfig := parseContainerConfig(container.ImageId)
+	if len(imgConfig.Config.StopSignal) > 0 {
+		if sig, err := parseSignal(imgConfig.Config.StopSignal); err == nil {
+			return sig
+		}
+		log.Printf("ignore invalid stop signal %s\n", imgConfig.Config.StopSignal)
+	}
+	return unix.SIGTERM
+}
+
+func waitForProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for isProcessRunning(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+func killContainerProcesses(containerId string, sig syscall.Signal) {
+	// without cgroups the processes die with the container init
+	if !canManageCGroups() {
+		return
+	}
+	pids, err := getContainerPids(containerId)
+	if err != nil {
+		log.Printf("get container processes failed: %v\n", err)
+		return
+	}
+	for _, pid := range pids {
+		unix.Kill(pid, sig)
+	}
+}
+
+func getRunningContainerState(containerId string) containerState {
+	container, err := loadContainerState(containerId)
+	if err != nil {
+		log.Fatalln("no such container")
+	}
+	if !isContainerRunning(container) {
+		log.Fatalf("container %s is not running\n", containerId)
+	}
+	return container
+}
+
+// finishStoppedContainer leaves recording the exit and the cleanup to the
+// supervisor of the container, it only does both itself when the supervisor
+// is gone.
+func finishStoppedContainer(container containerState, sig syscall.Signal) {
+	for {
+		state, err := loadContainerState(container.Id)
+		if err != nil || state.Status == containerStatusExited {
+			// a --rm container is removed by its supervisor
+			return
+		}
+		if !isContainerSupervised(state) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := updateContainerState(container.Id, func(state *containerState) {
+		if state.Status == containerStatusRunning {
+			state.Pid = 0
+			state.Status = containerStatusExited
+			state.ExitCode = 128 + int(sig)
+			state.FinishedAt = time.Now()
+		}
+	}); err != nil {
+		log.Printf("update container state failed: %v\n", err)
+	}
+	cleanupContainer(container.Id)
+}
+
+func stopContainer(containerId string, timeout int) {
+	container := getRunningContainerState(containerId)
+	sig := getStopSignal(container)
+	if err := signalContainerProcess(container.Pid, sig); err != nil {
+		log.Printf("send %s to container failed: %v\n", unix.SignalName(sig), err)
+	}
+	if !waitForProcessExit(container.Pid, time.Duration(timeout)*time.Second) {
+		log.Printf("container did not exit within %d seconds, killing it\n", timeout)
+		forceKillContainer(container)
+	} else {
+		finishStoppedContainer(container, sig)
+	}
+	fmt.Println(containerId)
+}
+
+func killContainer(containerId string, signal string) {
+	container := getRunningContainerState(containerId)
+	sig, err := parseSignal(signal)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := signalContainerProcess(container.Pid, sig); err != nil {
+		log.Fatalf("send %s to container failed: %v\n", unix.SignalName(sig), err)
+	}
+	if sig == unix.SIGKILL {
+		forceKillContainer(container)
+	}
+	fmt.Println(containerId)
+}
+
+func forceKillContainer(container containerState) {
+	signalContainerProcess(container.Pid, unix.SIGKILL)
+	killContainerProcesses(container.Id, unix.SIGKILL)
+	waitForProcessExit(container.Pid, 5*time.Second)
+	finishStoppedContainer(container, unix.SIGKILL)
+}
@@ -0,0 +1,421 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+const seccompNativeArch = unix.AUDIT_ARCH_ARM
+
+const seccompBigEndian = false
+
+const seccompSyscallBadBits = 0
+
+// seccompArchNames are the names seccomp profiles use for arm
+var seccompArchNames = []string{"SCMP_ARCH_ARM", "arm"}
+
+// syscallNumbers maps the kernel names of the arm system calls to their
+// numbers in golang.org/x/sys/unix.
+var syscallNumbers = map[string]int{
+	"syscall_mask":                 unix.SYS_SYSCALL_MASK,
+	"restart_syscall":              unix.SYS_RESTART_SYSCALL,
+	"exit":                         unix.SYS_EXIT,
+	"fork":                         unix.SYS_FORK,
+	"read":                         unix.SYS_READ,
+	"write":                        unix.SYS_WRITE,
+	"open":                         unix.SYS_OPEN,
+	"close":                        unix.SYS_CLOSE,
+	"creat":                        unix.SYS_CREAT,
+	"link":                         unix.SYS_LINK,
+	"unlink":                       unix.SYS_UNLINK,
+	"execve":                       unix.SYS_EXECVE,
+	"chdir":                        unix.SYS_CHDIR,
+	"mknod":                        unix.SYS_MKNOD,
+	"chmod":                        unix.SYS_CHMOD,
+	"lchown":                       unix.SYS_LCHOWN,
+	"lseek":                        unix.SYS_LSEEK,
+	"getpid":                       unix.SYS_GETPID,
+	"mount":                        unix.SYS_MOUNT,
+	"setuid":                       unix.SYS_SETUID,
+	"getuid":                       unix.SYS_GETUID,
+	"ptrace":                       unix.SYS_PTRACE,
+	"pause":                        unix.SYS_PAUSE,
+	"access":                       unix.SYS_ACCESS,
+	"nice":                         unix.SYS_NICE,
+	"sync":                         unix.SYS_SYNC,
+	"kill":                         unix.SYS_KILL,
+	"rename":                       unix.SYS_RENAME,
+	"mkdir":                        unix.SYS_MKDIR,
+	"rmdir":                        unix.SYS_RMDIR,
+	"dup":                          unix.SYS_DUP,
+	"pipe":                         unix.SYS_PIPE,
+	"times":                        unix.SYS_TIMES,
+	"brk":                          unix.SYS_BRK,
+	"setgid":                       unix.SYS_SETGID,
+	"getgid":                       unix.SYS_GETGID,
+	"geteuid":                      unix.SYS_GETEUID,
+	"getegid":                      unix.SYS_GETEGID,
+	"acct":                         unix.SYS_ACCT,
+	"umount2":                      unix.SYS_UMOUNT2,
+	"ioctl":                        unix.SYS_IOCTL,
+	"fcntl":                        unix.SYS_FCNTL,
+	"setpgid":                      unix.SYS_SETPGID,
+	"umask":                        unix.SYS_UMASK,
+	"chroot":                       unix.SYS_CHROOT,
+	"ustat":                        unix.SYS_USTAT,
+	"dup2":                         unix.SYS_DUP2,
+	"getppid":                      unix.SYS_GETPPID,
+	"getpgrp":                      unix.SYS_GETPGRP,
+	"setsid":                       unix.SYS_SETSID,
+	"sigaction":                    unix.SYS_SIGACTION,
+	"setreuid":                     unix.SYS_SETREUID,
+	"setregid":                     unix.SYS_SETREGID,
+	"sigsuspend":                   unix.SYS_SIGSUSPEND,
+	"sigpending":                   unix.SYS_SIGPENDING,
+	"sethostname":                  unix.SYS_SETHOSTNAME,
+	"setrlimit":                    unix.SYS_SETRLIMIT,
+	"getrusage":                    unix.SYS_GETRUSAGE,
+	"gettimeofday":                 unix.SYS_GETTIMEOFDAY,
+	"settimeofday":                 unix.SYS_SETTIMEOFDAY,
+	"getgroups":                    unix.SYS_GETGROUPS,
+	"setgroups":                    unix.SYS_SETGROUPS,
+	"symlink":                      unix.SYS_SYMLINK,
+	"readlink":                     unix.SYS_READLINK,
+	"uselib":                       unix.SYS_USELIB,
+	"swapon":                       unix.SYS_SWAPON,
+	"reboot":                       unix.SYS_REBOOT,
+	"munmap":                       unix.SYS_MUNMAP,
+	"truncate":                     unix.SYS_TRUNCATE,
+	"ftruncate":                    unix.SYS_FTRUNCATE,
+	"fchmod":                       unix.SYS_FCHMOD,
+	"fchown":                       unix.SYS_FCHOWN,
+	"getpriority":                  unix.SYS_GETPRIORITY,
+	"setpriority":                  unix.SYS_SETPRIORITY,
+	"statfs":                       unix.SYS_STATFS,
+	"fstatfs":                      unix.SYS_FSTATFS,
+	"syslog":                       unix.SYS_SYSLOG,
+	"setitimer":                    unix.SYS_SETITIMER,
+	"getitimer":                    unix.SYS_GETITIMER,
+	"stat":                         unix.SYS_STAT,
+	"lstat":                        unix.SYS_LSTAT,
+	"fstat":                        unix.SYS_FSTAT,
+	"vhangup":                      unix.SYS_VHANGUP,
+	"wait4":                        unix.SYS_WAIT4,
+	"swapoff":                      unix.SYS_SWAPOFF,
+	"sysinfo":                      unix.SYS_SYSINFO,
+	"fsync":                        unix.SYS_FSYNC,
+	"sigreturn":                    unix.SYS_SIGRETURN,
+	"clone":                        unix.SYS_CLONE,
+	"setdomainname":                unix.SYS_SETDOMAINNAME,
+	"uname":                        unix.SYS_UNAME,
+	"adjtimex":                     unix.SYS_ADJTIMEX,
+	"mprotect":                     unix.SYS_MPROTECT,
+	"sigprocmask":                  unix.SYS_SIGPROCMASK,
+	"init_module":                  unix.SYS_INIT_MODULE,
+	"delete_module":                unix.SYS_DELETE_MODULE,
+	"quotactl":                     unix.SYS_QUOTACTL,
+	"getpgid":                      unix.SYS_GETPGID,
+	"fchdir":                       unix.SYS_FCHDIR,
+	"bdflush":                      unix.SYS_BDFLUSH,
+	"sysfs":                        unix.SYS_SYSFS,
+	"personality":                  unix.SYS_PERSONALITY,
+	"setfsuid":                     unix.SYS_SETFSUID,
+	"setfsgid":                     unix.SYS_SETFSGID,
+	"_llseek":                      unix.SYS__LLSEEK,
+	"getdents":                     unix.SYS_GETDENTS,
+	"_newselect":                   unix.SYS__NEWSELECT,
+	"flock":                        unix.SYS_FLOCK,
+	"msync":                        unix.SYS_MSYNC,
+	"readv":                        unix.SYS_READV,
+	"writev":                       unix.SYS_WRITEV,
+	"getsid":                       unix.SYS_GETSID,
+	"fdatasync":                    unix.SYS_FDATASYNC,
+	"_sysctl":                      unix.SYS__SYSCTL,
+	"mlock":                        unix.SYS_MLOCK,
+	"munlock":                      unix.SYS_MUNLOCK,
+	"mlockall":                     unix.SYS_MLOCKALL,
+	"munlockall":                   unix.SYS_MUNLOCKALL,
+	"sched_setparam":               unix.SYS_SCHED_SETPARAM,
+	"sched_getparam":               unix.SYS_SCHED_GETPARAM,
+	"sched_setscheduler":           unix.SYS_SCHED_SETSCHEDULER,
+	"sched_getscheduler":           unix.SYS_SCHED_GETSCHEDULER,
+	"sched_yield":                  unix.SYS_SCHED_YIELD,
+	"sched_get_priority_max":       unix.SYS_SCHED_GET_PRIORITY_MAX,
+	"sched_get_priority_min":       unix.SYS_SCHED_GET_PRIORITY_MIN,
+	"sched_rr_get_interval":        unix.SYS_SCHED_RR_GET_INTERVAL,
+	"nanosleep":                    unix.SYS_NANOSLEEP,
+	"mremap":                       unix.SYS_MREMAP,
+	"setresuid":                    unix.SYS_SETRESUID,
+	"getresuid":                    unix.SYS_GETRESUID,
+	"poll":                         unix.SYS_POLL,
+	"nfsservctl":                   unix.SYS_NFSSERVCTL,
+	"setresgid":                    unix.SYS_SETRESGID,
+	"getresgid":                    unix.SYS_GETRESGID,
+	"prctl":                        unix.SYS_PRCTL,
+	"rt_sigreturn":                 unix.SYS_RT_SIGRETURN,
+	"rt_sigaction":                 unix.SYS_RT_SIGACTION,
+	"rt_sigprocmask":               unix.SYS_RT_SIGPROCMASK,
+	"rt_sigpending":                unix.SYS_RT_SIGPENDING,
+	"rt_sigtimedwait":              unix.SYS_RT_SIGTIMEDWAIT,
+	"rt_sigqueueinfo":              unix.SYS_RT_SIGQUEUEINFO,
+	"rt_sigsuspend":                unix.SYS_RT_SIGSUSPEND,
+	"pread64":                      unix.SYS_PREAD64,
+	"pwrite64":                     unix.SYS_PWRITE64,
+	"chown":                        unix.SYS_CHOWN,
+	"getcwd":                       unix.SYS_GETCWD,
+	"capget":                       unix.SYS_CAPGET,
+	"capset":                       unix.SYS_CAPSET,
+	"sigaltstack":                  unix.SYS_SIGALTSTACK,
+	"sendfile":                     unix.SYS_SENDFILE,
+	"vfork":                        unix.SYS_VFORK,
+	"ugetrlimit":                   unix.SYS_UGETRLIMIT,
+	"mmap2":                        unix.SYS_MMAP2,
+	"truncate64":                   unix.SYS_TRUNCATE64,
+	"ftruncate64":                  unix.SYS_FTRUNCATE64,
+	"stat64":                       unix.SYS_STAT64,
+	"lstat64":                      unix.SYS_LSTAT64,
+	"fstat64":                      unix.SYS_FSTAT64,
+	"lchown32":                     unix.SYS_LCHOWN32,
+	"getuid32":                     unix.SYS_GETUID32,
+	"getgid32":                     unix.SYS_GETGID32,
+	"geteuid32":                    unix.SYS_GETEUID32,
+	"getegid32":                    unix.SYS_GETEGID32,
+	"setreuid32":                   unix.SYS_SETREUID32,
+	"setregid32":                   unix.SYS_SETREGID32,
+	"getgroups32":                  unix.SYS_GETGROUPS32,
+	"setgroups32":                  unix.SYS_SETGROUPS32,
+	"fchown32":                     unix.SYS_FCHOWN32,
+	"setresuid32":                  unix.SYS_SETRESUID32,
+	"getresuid32":                  unix.SYS_GETRESUID32,
+	"setresgid32":                  unix.SYS_SETRESGID32,
+	"getresgid32":                  unix.SYS_GETRESGID32,
+	"chown32":                      unix.SYS_CHOWN32,
+	"setuid32":                     unix.SYS_SETUID32,
+	"setgid32":                     unix.SYS_SETGID32,
+	"setfsuid32":                   unix.SYS_SETFSUID32,
+	"setfsgid32":                   unix.SYS_SETFSGID32,
+	"getdents64":                   unix.SYS_GETDENTS64,
+	"pivot_root":                   unix.SYS_PIVOT_ROOT,
+	"mincore":                      unix.SYS_MINCORE,
+	"madvise":                      unix.SYS_MADVISE,
+	"fcntl64":                      unix.SYS_FCNTL64,
+	"gettid":                       unix.SYS_GETTID,
+	"readahead":                    unix.SYS_READAHEAD,
+	"setxattr":                     unix.SYS_SETXATTR,
+	"lsetxattr":                    unix.SYS_LSETXATTR,
+	"fsetxattr":                    unix.SYS_FSETXATTR,
+	"getxattr":                     unix.SYS_GETXATTR,
+	"lgetxattr":                    unix.SYS_LGETXATTR,
+	"fgetxattr":                    unix.SYS_FGETXATTR,
+	"listxattr":                    unix.SYS_LISTXATTR,
+	"llistxattr":                   unix.SYS_LLISTXATTR,
+	"flistxattr":                   unix.SYS_FLISTXATTR,
+	"removexattr":                  unix.SYS_REMOVEXATTR,
+	"lremovexattr":                 unix.SYS_LREMOVEXATTR,
+	"fremovexattr":                 unix.SYS_FREMOVEXATTR,
+	"tkill":                        unix.SYS_TKILL,
+	"sendfile64":                   unix.SYS_SENDFILE64,
+	"futex":                        unix.SYS_FUTEX,
+	"sched_setaffinity":            unix.SYS_SCHED_SETAFFINITY,
+	"sched_getaffinity":            unix.SYS_SCHED_GETAFFINITY,
+	"io_setup":                     unix.SYS_IO_SETUP,
+	"io_destroy":                   unix.SYS_IO_DESTROY,
+	"io_getevents":                 unix.SYS_IO_GETEVENTS,
+	"io_submit":                    unix.SYS_IO_SUBMIT,
+	"io_cancel":                    unix.SYS_IO_CANCEL,
+	"exit_group":                   unix.SYS_EXIT_GROUP,
+	"lookup_dcookie":               unix.SYS_LOOKUP_DCOOKIE,
+	"epoll_create":                 unix.SYS_EPOLL_CREATE,
+	"epoll_ctl":                    unix.SYS_EPOLL_CTL,
+	"epoll_wait":                   unix.SYS_EPOLL_WAIT,
+	"remap_file_pages":             unix.SYS_REMAP_FILE_PAGES,
+	"set_tid_address":              unix.SYS_SET_TID_ADDRESS,
+	"timer_create":                 unix.SYS_TIMER_CREATE,
+	"timer_settime":                unix.SYS_TIMER_SETTIME,
+	"timer_gettime":                unix.SYS_TIMER_GETTIME,
+	"timer_getoverrun":             unix.SYS_TIMER_GETOVERRUN,
+	"timer_delete":                 unix.SYS_TIMER_DELETE,
+	"clock_settime":                unix.SYS_CLOCK_SETTIME,
+	"clock_gettime":                unix.SYS_CLOCK_GETTIME,
+	"clock_getres":                 unix.SYS_CLOCK_GETRES,
+	"clock_nanosleep":              unix.SYS_CLOCK_NANOSLEEP,
+	"statfs64":                     unix.SYS_STATFS64,
+	"fstatfs64":                    unix.SYS_FSTATFS64,
+	"tgkill":                       unix.SYS_TGKILL,
+	"utimes":                       unix.SYS_UTIMES,
+	"arm_fadvise64_64":             unix.SYS_ARM_FADVISE64_64,
+	"pciconfig_iobase":             unix.SYS_PCICONFIG_IOBASE,
+	"pciconfig_read":               unix.SYS_PCICONFIG_READ,
+	"pciconfig_write":              unix.SYS_PCICONFIG_WRITE,
+	"mq_open":                      unix.SYS_MQ_OPEN,
+	"mq_unlink":                    unix.SYS_MQ_UNLINK,
+	"mq_timedsend":                 unix.SYS_MQ_TIMEDSEND,
+	"mq_timedreceive":              unix.SYS_MQ_TIMEDRECEIVE,
+	"mq_notify":                    unix.SYS_MQ_NOTIFY,
+	"mq_getsetattr":                unix.SYS_MQ_GETSETATTR,
+	"waitid":                       unix.SYS_WAITID,
+	"socket":                       unix.SYS_SOCKET,
+	"bind":                         unix.SYS_BIND,
+	"connect":                      unix.SYS_CONNECT,
+	"listen":                       unix.SYS_LISTEN,
+	"accept":                       unix.SYS_ACCEPT,
+	"getsockname":                  unix.SYS_GETSOCKNAME,
+	"getpeername":                  unix.SYS_GETPEERNAME,
+	"socketpair":                   unix.SYS_SOCKETPAIR,
+	"send":                         unix.SYS_SEND,
+	"sendto":                       unix.SYS_SENDTO,
+	"recv":                         unix.SYS_RECV,
+	"recvfrom":                     unix.SYS_RECVFROM,
+	"shutdown":                     unix.SYS_SHUTDOWN,
+	"setsockopt":                   unix.SYS_SETSOCKOPT,
+	"getsockopt":                   unix.SYS_GETSOCKOPT,
+	"sendmsg":                      unix.SYS_SENDMSG,
+	"recvmsg":                      unix.SYS_RECVMSG,
+	"semop":                        unix.SYS_SEMOP,
+	"semget":                       unix.SYS_SEMGET,
+	"semctl":                       unix.SYS_SEMCTL,
+	"msgsnd":                       unix.SYS_MSGSND,
+	"msgrcv":                       unix.SYS_MSGRCV,
+	"msgget":                       unix.SYS_MSGGET,
+	"msgctl":                       unix.SYS_MSGCTL,
+	"shmat":                        unix.SYS_SHMAT,
+	"shmdt":                        unix.SYS_SHMDT,
+	"shmget":                       unix.SYS_SHMGET,
+	"shmctl":                       unix.SYS_SHMCTL,
+	"add_key":                      unix.SYS_ADD_KEY,
+	"request_key":                  unix.SYS_REQUEST_KEY,
+	"keyctl":                       unix.SYS_KEYCTL,
+	"semtimedop":                   unix.SYS_SEMTIMEDOP,
+	"vserver":                      unix.SYS_VSERVER,
+	"ioprio_set":                   unix.SYS_IOPRIO_SET,
+	"ioprio_get":                   unix.SYS_IOPRIO_GET,
+	"inotify_init":                 unix.SYS_INOTIFY_INIT,
+	"inotify_add_watch":            unix.SYS_INOTIFY_ADD_WATCH,
+	"inotify_rm_watch":             unix.SYS_INOTIFY_RM_WATCH,
+	"mbind":                        unix.SYS_MBIND,
+	"get_mempolicy":                unix.SYS_GET_MEMPOLICY,
+	"set_mempolicy":                unix.SYS_SET_MEMPOLICY,
+	"openat":                       unix.SYS_OPENAT,
+	"mkdirat":                      unix.SYS_MKDIRAT,
+	"mknodat":                      unix.SYS_MKNODAT,
+	"fchownat":                     unix.SYS_FCHOWNAT,
+	"futimesat":                    unix.SYS_FUTIMESAT,
+	"fstatat64":                    unix.SYS_FSTATAT64,
+	"unlinkat":                     unix.SYS_UNLINKAT,
+	"renameat":                     unix.SYS_RENAMEAT,
+	"linkat":                       unix.SYS_LINKAT,
+	"symlinkat":                    unix.SYS_SYMLINKAT,
+	"readlinkat":                   unix.SYS_READLINKAT,
+	"fchmodat":                     unix.SYS_FCHMODAT,
+	"faccessat":                    unix.SYS_FACCESSAT,
+	"pselect6":                     unix.SYS_PSELECT6,
+	"ppoll":                        unix.SYS_PPOLL,
+	"unshare":                      unix.SYS_UNSHARE,
+	"set_robust_list":              unix.SYS_SET_ROBUST_LIST,
+	"get_robust_list":              unix.SYS_GET_ROBUST_LIST,
+	"splice":                       unix.SYS_SPLICE,
+	"arm_sync_file_range":          unix.SYS_ARM_SYNC_FILE_RANGE,
+	"tee":                          unix.SYS_TEE,
+	"vmsplice":                     unix.SYS_VMSPLICE,
+	"move_pages":                   unix.SYS_MOVE_PAGES,
+	"getcpu":                       unix.SYS_GETCPU,
+	"epoll_pwait":                  unix.SYS_EPOLL_PWAIT,
+	"kexec_load":                   unix.SYS_KEXEC_LOAD,
+	"utimensat":                    unix.SYS_UTIMENSAT,
+	"signalfd":                     unix.SYS_SIGNALFD,
+	"timerfd_create":               unix.SYS_TIMERFD_CREATE,
+	"eventfd":                      unix.SYS_EVENTFD,
+	"fallocate":                    unix.SYS_FALLOCATE,
+	"timerfd_settime":              unix.SYS_TIMERFD_SETTIME,
+	"timerfd_gettime":              unix.SYS_TIMERFD_GETTIME,
+	"signalfd4":                    unix.SYS_SIGNALFD4,
+	"eventfd2":                     unix.SYS_EVENTFD2,
+	"epoll_create1":                unix.SYS_EPOLL_CREATE1,
+	"dup3":                         unix.SYS_DUP3,
+	"pipe2":                        unix.SYS_PIPE2,
+	"inotify_init1":                unix.SYS_INOTIFY_INIT1,
+	"preadv":                       unix.SYS_PREADV,
+	"pwritev":                      unix.SYS_PWRITEV,
+	"rt_tgsigqueueinfo":            unix.SYS_RT_TGSIGQUEUEINFO,
+	"perf_event_open":              unix.SYS_PERF_EVENT_OPEN,
+	"recvmmsg":                     unix.SYS_RECVMMSG,
+	"accept4":                      unix.SYS_ACCEPT4,
+	"fanotify_init":                unix.SYS_FANOTIFY_INIT,
+	"fanotify_mark":                unix.SYS_FANOTIFY_MARK,
+	"prlimit64":                    unix.SYS_PRLIMIT64,
+	"name_to_handle_at":            unix.SYS_NAME_TO_HANDLE_AT,
+	"open_by_handle_at":            unix.SYS_OPEN_BY_HANDLE_AT,
+	"clock_adjtime":                unix.SYS_CLOCK_ADJTIME,
+	"syncfs":                       unix.SYS_SYNCFS,
+	"sendmmsg":                     unix.SYS_SENDMMSG,
+	"setns":                        unix.SYS_SETNS,
+	"process_vm_readv":             unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev":            unix.SYS_PROCESS_VM_WRITEV,
+	"kcmp":                         unix.SYS_KCMP,
+	"finit_module":                 unix.SYS_FINIT_MODULE,
+	"sched_setattr":                unix.SYS_SCHED_SETATTR,
+	"sched_getattr":                unix.SYS_SCHED_GETATTR,
+	"renameat2":                    unix.SYS_RENAMEAT2,
+	"seccomp":                      unix.SYS_SECCOMP,
+	"getrandom":                    unix.SYS_GETRANDOM,
+	"memfd_create":                 unix.SYS_MEMFD_CREATE,
+	"bpf":                          unix.SYS_BPF,
+	"execveat":                     unix.SYS_EXECVEAT,
+	"userfaultfd":                  unix.SYS_USERFAULTFD,
+	"membarrier":                   unix.SYS_MEMBARRIER,
+	"mlock2":                       unix.SYS_MLOCK2,
+	"copy_file_range":              unix.SYS_COPY_FILE_RANGE,
+	"preadv2":                      unix.SYS_PREADV2,
+	"pwritev2":                     unix.SYS_PWRITEV2,
+	"pkey_mprotect":                unix.SYS_PKEY_MPROTECT,
+	"pkey_alloc":                   unix.SYS_PKEY_ALLOC,
+	"pkey_free":                    unix.SYS_PKEY_FREE,
+	"statx":                        unix.SYS_STATX,
+	"rseq":                         unix.SYS_RSEQ,
+	"io_pgetevents":                unix.SYS_IO_PGETEVENTS,
+	"migrate_pages":                unix.SYS_MIGRATE_PAGES,
+	"kexec_file_load":              unix.SYS_KEXEC_FILE_LOAD,
+	"clock_gettime64":              unix.SYS_CLOCK_GETTIME64,
+	"clock_settime64":              unix.SYS_CLOCK_SETTIME64,
+	"clock_adjtime64":              unix.SYS_CLOCK_ADJTIME64,
+	"clock_getres_time64":          unix.SYS_CLOCK_GETRES_TIME64,
+	"clock_nanosleep_time64":       unix.SYS_CLOCK_NANOSLEEP_TIME64,
+	"timer_gettime64":              unix.SYS_TIMER_GETTIME64,
+	"timer_settime64":              unix.SYS_TIMER_SETTIME64,
+	"timerfd_gettime64":            unix.SYS_TIMERFD_GETTIME64,
+	"timerfd_settime64":            unix.SYS_TIMERFD_SETTIME64,
+	"utimensat_time64":             unix.SYS_UTIMENSAT_TIME64,
+	"pselect6_time64":              unix.SYS_PSELECT6_TIME64,
+	"ppoll_time64":                 unix.SYS_PPOLL_TIME64,
+	"io_pgetevents_time64":         unix.SYS_IO_PGETEVENTS_TIME64,
+	"recvmmsg_time64":              unix.SYS_RECVMMSG_TIME64,
+	"mq_timedsend_time64":          unix.SYS_MQ_TIMEDSEND_TIME64,
+	"mq_timedreceive_time64":       unix.SYS_MQ_TIMEDRECEIVE_TIME64,
+	"semtimedop_time64":            unix.SYS_SEMTIMEDOP_TIME64,
+	"rt_sigtimedwait_time64":       unix.SYS_RT_SIGTIMEDWAIT_TIME64,
+	"futex_time64":                 unix.SYS_FUTEX_TIME64,
+	"sched_rr_get_interval_time64": unix.SYS_SCHED_RR_GET_INTERVAL_TIME64,
+	"pidfd_send_signal":            unix.SYS_PIDFD_SEND_SIGNAL,
+	"io_uring_setup":               unix.SYS_IO_URING_SETUP,
+	"io_uring_enter":               unix.SYS_IO_URING_ENTER,
+	"io_uring_register":            unix.SYS_IO_URING_REGISTER,
+	"open_tree":                    unix.SYS_OPEN_TREE,
+	"move_mount":                   unix.SYS_MOVE_MOUNT,
+	"fsopen":                       unix.SYS_FSOPEN,
+	"fsconfig":                     unix.SYS_FSCONFIG,
+	"fsmount":                      unix.SYS_FSMOUNT,
+	"fspick":                       unix.SYS_FSPICK,
+	"pidfd_open":                   unix.SYS_PIDFD_OPEN,
+	"clone3":                       unix.SYS_CLONE3,
+	"close_range":                  unix.SYS_CLOSE_RANGE,
+	"openat2":                      unix.SYS_OPENAT2,
+	"pidfd_getfd":                  unix.SYS_PIDFD_GETFD,
+	"faccessat2":                   unix.SYS_FACCESSAT2,
+	"process_madvise":              unix.SYS_PROCESS_MADVISE,
+	"epoll_pwait2":                 unix.SYS_EPOLL_PWAIT2,
+	"mount_setattr":                unix.SYS_MOUNT_SETATTR,
+	"quotactl_fd":                  unix.SYS_QUOTACTL_FD,
+	"landlock_create_ruleset":      unix.SYS_LANDLOCK_CREATE_RULESET,
+	"landlock_add_rule":            unix.SYS_LANDLOCK_ADD_RULE,
+	"landlock_restrict_self":       unix.SYS_LANDLOCK_RESTRICT_SELF,
+	"process_mrelease":             unix.SYS_PROCESS_MRELEASE,
+	"futex_waitv":                  unix.SYS_FUTEX_WAITV,
+	"set_mempolicy_home_node":      unix.SYS_SET_MEMPOLICY_HOME_NODE,
+}
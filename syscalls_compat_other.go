@@ -0,0 +1,7 @@
+//go:build !amd64 && !arm64
+
+package main
+
+// the remaining architectures run no other architecture's system calls, or
+// none that seccomp profiles tell apart
+var seccompSubArches []seccompSubArch
@@ -0,0 +1,15 @@
+//go:build !386 && !amd64 && !arm && !arm64 && !ppc64le && !riscv64 && !s390x
+
+package main
+
+// seccomp filtering is not supported on the remaining architectures, the
+// containers there run unconfined and an explicit profile is refused
+const seccompNativeArch = 0
+
+const seccompBigEndian = false
+
+const seccompSyscallBadBits = 0
+
+var seccompArchNames []string
+
+var syscallNumbers = map[string]int{}
@@ -0,0 +1,420 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+const seccompNativeArch = unix.AUDIT_ARCH_PPC64LE
+
+const seccompBigEndian = false
+
+const seccompSyscallBadBits = 0
+
+// seccompArchNames are the names seccomp profiles use for ppc64le
+var seccompArchNames = []string{"SCMP_ARCH_PPC64LE", "ppc64le"}
+
+// syscallNumbers maps the kernel names of the ppc64le system calls to their
+// numbers in golang.org/x/sys/unix.
+var syscallNumbers = map[string]int{
+	"restart_syscall":         unix.SYS_RESTART_SYSCALL,
+	"exit":                    unix.SYS_EXIT,
+	"fork":                    unix.SYS_FORK,
+	"read":                    unix.SYS_READ,
+	"write":                   unix.SYS_WRITE,
+	"open":                    unix.SYS_OPEN,
+	"close":                   unix.SYS_CLOSE,
+	"waitpid":                 unix.SYS_WAITPID,
+	"creat":                   unix.SYS_CREAT,
+	"link":                    unix.SYS_LINK,
+	"unlink":                  unix.SYS_UNLINK,
+	"execve":                  unix.SYS_EXECVE,
+	"chdir":                   unix.SYS_CHDIR,
+	"time":                    unix.SYS_TIME,
+	"mknod":                   unix.SYS_MKNOD,
+	"chmod":                   unix.SYS_CHMOD,
+	"lchown":                  unix.SYS_LCHOWN,
+	"break":                   unix.SYS_BREAK,
+	"oldstat":                 unix.SYS_OLDSTAT,
+	"lseek":                   unix.SYS_LSEEK,
+	"getpid":                  unix.SYS_GETPID,
+	"mount":                   unix.SYS_MOUNT,
+	"umount":                  unix.SYS_UMOUNT,
+	"setuid":                  unix.SYS_SETUID,
+	"getuid":                  unix.SYS_GETUID,
+	"stime":                   unix.SYS_STIME,
+	"ptrace":                  unix.SYS_PTRACE,
+	"alarm":                   unix.SYS_ALARM,
+	"oldfstat":                unix.SYS_OLDFSTAT,
+	"pause":                   unix.SYS_PAUSE,
+	"utime":                   unix.SYS_UTIME,
+	"stty":                    unix.SYS_STTY,
+	"gtty":                    unix.SYS_GTTY,
+	"access":                  unix.SYS_ACCESS,
+	"nice":                    unix.SYS_NICE,
+	"ftime":                   unix.SYS_FTIME,
+	"sync":                    unix.SYS_SYNC,
+	"kill":                    unix.SYS_KILL,
+	"rename":                  unix.SYS_RENAME,
+	"mkdir":                   unix.SYS_MKDIR,
+	"rmdir":                   unix.SYS_RMDIR,
+	"dup":                     unix.SYS_DUP,
+	"pipe":                    unix.SYS_PIPE,
+	"times":                   unix.SYS_TIMES,
+	"prof":                    unix.SYS_PROF,
+	"brk":                     unix.SYS_BRK,
+	"setgid":                  unix.SYS_SETGID,
+	"getgid":                  unix.SYS_GETGID,
+	"signal":                  unix.SYS_SIGNAL,
+	"geteuid":                 unix.SYS_GETEUID,
+	"getegid":                 unix.SYS_GETEGID,
+	"acct":                    unix.SYS_ACCT,
+	"umount2":                 unix.SYS_UMOUNT2,
+	"lock":                    unix.SYS_LOCK,
+	"ioctl":                   unix.SYS_IOCTL,
+	"fcntl":                   unix.SYS_FCNTL,
+	"mpx":                     unix.SYS_MPX,
+	"setpgid":                 unix.SYS_SETPGID,
+	"ulimit":                  unix.SYS_ULIMIT,
+	"oldolduname":             unix.SYS_OLDOLDUNAME,
+	"umask":                   unix.SYS_UMASK,
+	"chroot":                  unix.SYS_CHROOT,
+	"ustat":                   unix.SYS_USTAT,
+	"dup2":                    unix.SYS_DUP2,
+	"getppid":                 unix.SYS_GETPPID,
+	"getpgrp":                 unix.SYS_GETPGRP,
+	"setsid":                  unix.SYS_SETSID,
+	"sigaction":               unix.SYS_SIGACTION,
+	"sgetmask":                unix.SYS_SGETMASK,
+	"ssetmask":                unix.SYS_SSETMASK,
+	"setreuid":                unix.SYS_SETREUID,
+	"setregid":                unix.SYS_SETREGID,
+	"sigsuspend":              unix.SYS_SIGSUSPEND,
+	"sigpending":              unix.SYS_SIGPENDING,
+	"sethostname":             unix.SYS_SETHOSTNAME,
+	"setrlimit":               unix.SYS_SETRLIMIT,
+	"getrlimit":               unix.SYS_GETRLIMIT,
+	"getrusage":               unix.SYS_GETRUSAGE,
+	"gettimeofday":            unix.SYS_GETTIMEOFDAY,
+	"settimeofday":            unix.SYS_SETTIMEOFDAY,
+	"getgroups":               unix.SYS_GETGROUPS,
+	"setgroups":               unix.SYS_SETGROUPS,
+	"select":                  unix.SYS_SELECT,
+	"symlink":                 unix.SYS_SYMLINK,
+	"oldlstat":                unix.SYS_OLDLSTAT,
+	"readlink":                unix.SYS_READLINK,
+	"uselib":                  unix.SYS_USELIB,
+	"swapon":                  unix.SYS_SWAPON,
+	"reboot":                  unix.SYS_REBOOT,
+	"readdir":                 unix.SYS_READDIR,
+	"mmap":                    unix.SYS_MMAP,
+	"munmap":                  unix.SYS_MUNMAP,
+	"truncate":                unix.SYS_TRUNCATE,
+	"ftruncate":               unix.SYS_FTRUNCATE,
+	"fchmod":                  unix.SYS_FCHMOD,
+	"fchown":                  unix.SYS_FCHOWN,
+	"getpriority":             unix.SYS_GETPRIORITY,
+	"setpriority":             unix.SYS_SETPRIORITY,
+	"profil":                  unix.SYS_PROFIL,
+	"statfs":                  unix.SYS_STATFS,
+	"fstatfs":                 unix.SYS_FSTATFS,
+	"ioperm":                  unix.SYS_IOPERM,
+	"socketcall":              unix.SYS_SOCKETCALL,
+	"syslog":                  unix.SYS_SYSLOG,
+	"setitimer":               unix.SYS_SETITIMER,
+	"getitimer":               unix.SYS_GETITIMER,
+	"stat":                    unix.SYS_STAT,
+	"lstat":                   unix.SYS_LSTAT,
+	"fstat":                   unix.SYS_FSTAT,
+	"olduname":                unix.SYS_OLDUNAME,
+	"iopl":                    unix.SYS_IOPL,
+	"vhangup":                 unix.SYS_VHANGUP,
+	"idle":                    unix.SYS_IDLE,
+	"vm86":                    unix.SYS_VM86,
+	"wait4":                   unix.SYS_WAIT4,
+	"swapoff":                 unix.SYS_SWAPOFF,
+	"sysinfo":                 unix.SYS_SYSINFO,
+	"ipc":                     unix.SYS_IPC,
+	"fsync":                   unix.SYS_FSYNC,
+	"sigreturn":               unix.SYS_SIGRETURN,
+	"clone":                   unix.SYS_CLONE,
+	"setdomainname":           unix.SYS_SETDOMAINNAME,
+	"uname":                   unix.SYS_UNAME,
+	"modify_ldt":              unix.SYS_MODIFY_LDT,
+	"adjtimex":                unix.SYS_ADJTIMEX,
+	"mprotect":                unix.SYS_MPROTECT,
+	"sigprocmask":             unix.SYS_SIGPROCMASK,
+	"create_module":           unix.SYS_CREATE_MODULE,
+	"init_module":             unix.SYS_INIT_MODULE,
+	"delete_module":           unix.SYS_DELETE_MODULE,
+	"get_kernel_syms":         unix.SYS_GET_KERNEL_SYMS,
+	"quotactl":                unix.SYS_QUOTACTL,
+	"getpgid":                 unix.SYS_GETPGID,
+	"fchdir":                  unix.SYS_FCHDIR,
+	"bdflush":                 unix.SYS_BDFLUSH,
+	"sysfs":                   unix.SYS_SYSFS,
+	"personality":             unix.SYS_PERSONALITY,
+	"afs_syscall":             unix.SYS_AFS_SYSCALL,
+	"setfsuid":                unix.SYS_SETFSUID,
+	"setfsgid":                unix.SYS_SETFSGID,
+	"_llseek":                 unix.SYS__LLSEEK,
+	"getdents":                unix.SYS_GETDENTS,
+	"_newselect":              unix.SYS__NEWSELECT,
+	"flock":                   unix.SYS_FLOCK,
+	"msync":                   unix.SYS_MSYNC,
+	"readv":                   unix.SYS_READV,
+	"writev":                  unix.SYS_WRITEV,
+	"getsid":                  unix.SYS_GETSID,
+	"fdatasync":               unix.SYS_FDATASYNC,
+	"_sysctl":                 unix.SYS__SYSCTL,
+	"mlock":                   unix.SYS_MLOCK,
+	"munlock":                 unix.SYS_MUNLOCK,
+	"mlockall":                unix.SYS_MLOCKALL,
+	"munlockall":              unix.SYS_MUNLOCKALL,
+	"sched_setparam":          unix.SYS_SCHED_SETPARAM,
+	"sched_getparam":          unix.SYS_SCHED_GETPARAM,
+	"sched_setscheduler":      unix.SYS_SCHED_SETSCHEDULER,
+	"sched_getscheduler":      unix.SYS_SCHED_GETSCHEDULER,
+	"sched_yield":             unix.SYS_SCHED_YIELD,
+	"sched_get_priority_max":  unix.SYS_SCHED_GET_PRIORITY_MAX,
+	"sched_get_priority_min":  unix.SYS_SCHED_GET_PRIORITY_MIN,
+	"sched_rr_get_interval":   unix.SYS_SCHED_RR_GET_INTERVAL,
+	"nanosleep":               unix.SYS_NANOSLEEP,
+	"mremap":                  unix.SYS_MREMAP,
+	"setresuid":               unix.SYS_SETRESUID,
+	"getresuid":               unix.SYS_GETRESUID,
+	"query_module":            unix.SYS_QUERY_MODULE,
+	"poll":                    unix.SYS_POLL,
+	"nfsservctl":              unix.SYS_NFSSERVCTL,
+	"setresgid":               unix.SYS_SETRESGID,
+	"getresgid":               unix.SYS_GETRESGID,
+	"prctl":                   unix.SYS_PRCTL,
+	"rt_sigreturn":            unix.SYS_RT_SIGRETURN,
+	"rt_sigaction":            unix.SYS_RT_SIGACTION,
+	"rt_sigprocmask":          unix.SYS_RT_SIGPROCMASK,
+	"rt_sigpending":           unix.SYS_RT_SIGPENDING,
+	"rt_sigtimedwait":         unix.SYS_RT_SIGTIMEDWAIT,
+	"rt_sigqueueinfo":         unix.SYS_RT_SIGQUEUEINFO,
+	"rt_sigsuspend":           unix.SYS_RT_SIGSUSPEND,
+	"pread64":                 unix.SYS_PREAD64,
+	"pwrite64":                unix.SYS_PWRITE64,
+	"chown":                   unix.SYS_CHOWN,
+	"getcwd":                  unix.SYS_GETCWD,
+	"capget":                  unix.SYS_CAPGET,
+	"capset":                  unix.SYS_CAPSET,
+	"sigaltstack":             unix.SYS_SIGALTSTACK,
+	"sendfile":                unix.SYS_SENDFILE,
+	"getpmsg":                 unix.SYS_GETPMSG,
+	"putpmsg":                 unix.SYS_PUTPMSG,
+	"vfork":                   unix.SYS_VFORK,
+	"ugetrlimit":              unix.SYS_UGETRLIMIT,
+	"readahead":               unix.SYS_READAHEAD,
+	"pciconfig_read":          unix.SYS_PCICONFIG_READ,
+	"pciconfig_write":         unix.SYS_PCICONFIG_WRITE,
+	"pciconfig_iobase":        unix.SYS_PCICONFIG_IOBASE,
+	"multiplexer":             unix.SYS_MULTIPLEXER,
+	"getdents64":              unix.SYS_GETDENTS64,
+	"pivot_root":              unix.SYS_PIVOT_ROOT,
+	"madvise":                 unix.SYS_MADVISE,
+	"mincore":                 unix.SYS_MINCORE,
+	"gettid":                  unix.SYS_GETTID,
+	"tkill":                   unix.SYS_TKILL,
+	"setxattr":                unix.SYS_SETXATTR,
+	"lsetxattr":               unix.SYS_LSETXATTR,
+	"fsetxattr":               unix.SYS_FSETXATTR,
+	"getxattr":                unix.SYS_GETXATTR,
+	"lgetxattr":               unix.SYS_LGETXATTR,
+	"fgetxattr":               unix.SYS_FGETXATTR,
+	"listxattr":               unix.SYS_LISTXATTR,
+	"llistxattr":              unix.SYS_LLISTXATTR,
+	"flistxattr":              unix.SYS_FLISTXATTR,
+	"removexattr":             unix.SYS_REMOVEXATTR,
+	"lremovexattr":            unix.SYS_LREMOVEXATTR,
+	"fremovexattr":            unix.SYS_FREMOVEXATTR,
+	"futex":                   unix.SYS_FUTEX,
+	"sched_setaffinity":       unix.SYS_SCHED_SETAFFINITY,
+	"sched_getaffinity":       unix.SYS_SCHED_GETAFFINITY,
+	"tuxcall":                 unix.SYS_TUXCALL,
+	"io_setup":                unix.SYS_IO_SETUP,
+	"io_destroy":              unix.SYS_IO_DESTROY,
+	"io_getevents":            unix.SYS_IO_GETEVENTS,
+	"io_submit":               unix.SYS_IO_SUBMIT,
+	"io_cancel":               unix.SYS_IO_CANCEL,
+	"set_tid_address":         unix.SYS_SET_TID_ADDRESS,
+	"fadvise64":               unix.SYS_FADVISE64,
+	"exit_group":              unix.SYS_EXIT_GROUP,
+	"lookup_dcookie":          unix.SYS_LOOKUP_DCOOKIE,
+	"epoll_create":            unix.SYS_EPOLL_CREATE,
+	"epoll_ctl":               unix.SYS_EPOLL_CTL,
+	"epoll_wait":              unix.SYS_EPOLL_WAIT,
+	"remap_file_pages":        unix.SYS_REMAP_FILE_PAGES,
+	"timer_create":            unix.SYS_TIMER_CREATE,
+	"timer_settime":           unix.SYS_TIMER_SETTIME,
+	"timer_gettime":           unix.SYS_TIMER_GETTIME,
+	"timer_getoverrun":        unix.SYS_TIMER_GETOVERRUN,
+	"timer_delete":            unix.SYS_TIMER_DELETE,
+	"clock_settime":           unix.SYS_CLOCK_SETTIME,
+	"clock_gettime":           unix.SYS_CLOCK_GETTIME,
+	"clock_getres":            unix.SYS_CLOCK_GETRES,
+	"clock_nanosleep":         unix.SYS_CLOCK_NANOSLEEP,
+	"swapcontext":             unix.SYS_SWAPCONTEXT,
+	"tgkill":                  unix.SYS_TGKILL,
+	"utimes":                  unix.SYS_UTIMES,
+	"statfs64":                unix.SYS_STATFS64,
+	"fstatfs64":               unix.SYS_FSTATFS64,
+	"rtas":                    unix.SYS_RTAS,
+	"sys_debug_setcontext":    unix.SYS_SYS_DEBUG_SETCONTEXT,
+	"migrate_pages":           unix.SYS_MIGRATE_PAGES,
+	"mbind":                   unix.SYS_MBIND,
+	"get_mempolicy":           unix.SYS_GET_MEMPOLICY,
+	"set_mempolicy":           unix.SYS_SET_MEMPOLICY,
+	"mq_open":                 unix.SYS_MQ_OPEN,
+	"mq_unlink":               unix.SYS_MQ_UNLINK,
+	"mq_timedsend":            unix.SYS_MQ_TIMEDSEND,
+	"mq_timedreceive":         unix.SYS_MQ_TIMEDRECEIVE,
+	"mq_notify":               unix.SYS_MQ_NOTIFY,
+	"mq_getsetattr":           unix.SYS_MQ_GETSETATTR,
+	"kexec_load":              unix.SYS_KEXEC_LOAD,
+	"add_key":                 unix.SYS_ADD_KEY,
+	"request_key":             unix.SYS_REQUEST_KEY,
+	"keyctl":                  unix.SYS_KEYCTL,
+	"waitid":                  unix.SYS_WAITID,
+	"ioprio_set":              unix.SYS_IOPRIO_SET,
+	"ioprio_get":              unix.SYS_IOPRIO_GET,
+	"inotify_init":            unix.SYS_INOTIFY_INIT,
+	"inotify_add_watch":       unix.SYS_INOTIFY_ADD_WATCH,
+	"inotify_rm_watch":        unix.SYS_INOTIFY_RM_WATCH,
+	"spu_run":                 unix.SYS_SPU_RUN,
+	"spu_create":              unix.SYS_SPU_CREATE,
+	"pselect6":                unix.SYS_PSELECT6,
+	"ppoll":                   unix.SYS_PPOLL,
+	"unshare":                 unix.SYS_UNSHARE,
+	"splice":                  unix.SYS_SPLICE,
+	"tee":                     unix.SYS_TEE,
+	"vmsplice":                unix.SYS_VMSPLICE,
+	"openat":                  unix.SYS_OPENAT,
+	"mkdirat":                 unix.SYS_MKDIRAT,
+	"mknodat":                 unix.SYS_MKNODAT,
+	"fchownat":                unix.SYS_FCHOWNAT,
+	"futimesat":               unix.SYS_FUTIMESAT,
+	"newfstatat":              unix.SYS_NEWFSTATAT,
+	"unlinkat":                unix.SYS_UNLINKAT,
+	"renameat":                unix.SYS_RENAMEAT,
+	"linkat":                  unix.SYS_LINKAT,
+	"symlinkat":               unix.SYS_SYMLINKAT,
+	"readlinkat":              unix.SYS_READLINKAT,
+	"fchmodat":                unix.SYS_FCHMODAT,
+	"faccessat":               unix.SYS_FACCESSAT,
+	"get_robust_list":         unix.SYS_GET_ROBUST_LIST,
+	"set_robust_list":         unix.SYS_SET_ROBUST_LIST,
+	"move_pages":              unix.SYS_MOVE_PAGES,
+	"getcpu":                  unix.SYS_GETCPU,
+	"epoll_pwait":             unix.SYS_EPOLL_PWAIT,
+	"utimensat":               unix.SYS_UTIMENSAT,
+	"signalfd":                unix.SYS_SIGNALFD,
+	"timerfd_create":          unix.SYS_TIMERFD_CREATE,
+	"eventfd":                 unix.SYS_EVENTFD,
+	"sync_file_range2":        unix.SYS_SYNC_FILE_RANGE2,
+	"fallocate":               unix.SYS_FALLOCATE,
+	"subpage_prot":            unix.SYS_SUBPAGE_PROT,
+	"timerfd_settime":         unix.SYS_TIMERFD_SETTIME,
+	"timerfd_gettime":         unix.SYS_TIMERFD_GETTIME,
+	"signalfd4":               unix.SYS_SIGNALFD4,
+	"eventfd2":                unix.SYS_EVENTFD2,
+	"epoll_create1":           unix.SYS_EPOLL_CREATE1,
+	"dup3":                    unix.SYS_DUP3,
+	"pipe2":                   unix.SYS_PIPE2,
+	"inotify_init1":           unix.SYS_INOTIFY_INIT1,
+	"perf_event_open":         unix.SYS_PERF_EVENT_OPEN,
+	"preadv":                  unix.SYS_PREADV,
+	"pwritev":                 unix.SYS_PWRITEV,
+	"rt_tgsigqueueinfo":       unix.SYS_RT_TGSIGQUEUEINFO,
+	"fanotify_init":           unix.SYS_FANOTIFY_INIT,
+	"fanotify_mark":           unix.SYS_FANOTIFY_MARK,
+	"prlimit64":               unix.SYS_PRLIMIT64,
+	"socket":                  unix.SYS_SOCKET,
+	"bind":                    unix.SYS_BIND,
+	"connect":                 unix.SYS_CONNECT,
+	"listen":                  unix.SYS_LISTEN,
+	"accept":                  unix.SYS_ACCEPT,
+	"getsockname":             unix.SYS_GETSOCKNAME,
+	"getpeername":             unix.SYS_GETPEERNAME,
+	"socketpair":              unix.SYS_SOCKETPAIR,
+	"send":                    unix.SYS_SEND,
+	"sendto":                  unix.SYS_SENDTO,
+	"recv":                    unix.SYS_RECV,
+	"recvfrom":                unix.SYS_RECVFROM,
+	"shutdown":                unix.SYS_SHUTDOWN,
+	"setsockopt":              unix.SYS_SETSOCKOPT,
+	"getsockopt":              unix.SYS_GETSOCKOPT,
+	"sendmsg":                 unix.SYS_SENDMSG,
+	"recvmsg":                 unix.SYS_RECVMSG,
+	"recvmmsg":                unix.SYS_RECVMMSG,
+	"accept4":                 unix.SYS_ACCEPT4,
+	"name_to_handle_at":       unix.SYS_NAME_TO_HANDLE_AT,
+	"open_by_handle_at":       unix.SYS_OPEN_BY_HANDLE_AT,
+	"clock_adjtime":           unix.SYS_CLOCK_ADJTIME,
+	"syncfs":                  unix.SYS_SYNCFS,
+	"sendmmsg":                unix.SYS_SENDMMSG,
+	"setns":                   unix.SYS_SETNS,
+	"process_vm_readv":        unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev":       unix.SYS_PROCESS_VM_WRITEV,
+	"finit_module":            unix.SYS_FINIT_MODULE,
+	"kcmp":                    unix.SYS_KCMP,
+	"sched_setattr":           unix.SYS_SCHED_SETATTR,
+	"sched_getattr":           unix.SYS_SCHED_GETATTR,
+	"renameat2":               unix.SYS_RENAMEAT2,
+	"seccomp":                 unix.SYS_SECCOMP,
+	"getrandom":               unix.SYS_GETRANDOM,
+	"memfd_create":            unix.SYS_MEMFD_CREATE,
+	"bpf":                     unix.SYS_BPF,
+	"execveat":                unix.SYS_EXECVEAT,
+	"switch_endian":           unix.SYS_SWITCH_ENDIAN,
+	"userfaultfd":             unix.SYS_USERFAULTFD,
+	"membarrier":              unix.SYS_MEMBARRIER,
+	"mlock2":                  unix.SYS_MLOCK2,
+	"copy_file_range":         unix.SYS_COPY_FILE_RANGE,
+	"preadv2":                 unix.SYS_PREADV2,
+	"pwritev2":                unix.SYS_PWRITEV2,
+	"kexec_file_load":         unix.SYS_KEXEC_FILE_LOAD,
+	"statx":                   unix.SYS_STATX,
+	"pkey_alloc":              unix.SYS_PKEY_ALLOC,
+	"pkey_free":               unix.SYS_PKEY_FREE,
+	"pkey_mprotect":           unix.SYS_PKEY_MPROTECT,
+	"rseq":                    unix.SYS_RSEQ,
+	"io_pgetevents":           unix.SYS_IO_PGETEVENTS,
+	"semtimedop":              unix.SYS_SEMTIMEDOP,
+	"semget":                  unix.SYS_SEMGET,
+	"semctl":                  unix.SYS_SEMCTL,
+	"shmget":                  unix.SYS_SHMGET,
+	"shmctl":                  unix.SYS_SHMCTL,
+	"shmat":                   unix.SYS_SHMAT,
+	"shmdt":                   unix.SYS_SHMDT,
+	"msgget":                  unix.SYS_MSGGET,
+	"msgsnd":                  unix.SYS_MSGSND,
+	"msgrcv":                  unix.SYS_MSGRCV,
+	"msgctl":                  unix.SYS_MSGCTL,
+	"pidfd_send_signal":       unix.SYS_PIDFD_SEND_SIGNAL,
+	"io_uring_setup":          unix.SYS_IO_URING_SETUP,
+	"io_uring_enter":          unix.SYS_IO_URING_ENTER,
+	"io_uring_register":       unix.SYS_IO_URING_REGISTER,
+	"open_tree":               unix.SYS_OPEN_TREE,
+	"move_mount":              unix.SYS_MOVE_MOUNT,
+	"fsopen":                  unix.SYS_FSOPEN,
+	"fsconfig":                unix.SYS_FSCONFIG,
+	"fsmount":                 unix.SYS_FSMOUNT,
+	"fspick":                  unix.SYS_FSPICK,
+	"pidfd_open":              unix.SYS_PIDFD_OPEN,
+	"clone3":                  unix.SYS_CLONE3,
+	"close_range":             unix.SYS_CLOSE_RANGE,
+	"openat2":                 unix.SYS_OPENAT2,
+	"pidfd_getfd":             unix.SYS_PIDFD_GETFD,
+	"faccessat2":              unix.SYS_FACCESSAT2,
+	"process_madvise":         unix.SYS_PROCESS_MADVISE,
+	"epoll_pwait2":            unix.SYS_EPOLL_PWAIT2,
+	"mount_setattr":           unix.SYS_MOUNT_SETATTR,
+	"quotactl_fd":             unix.SYS_QUOTACTL_FD,
+	"landlock_create_ruleset": unix.SYS_LANDLOCK_CREATE_RULESET,
+	"landlock_add_rule":       unix.SYS_LANDLOCK_ADD_RULE,
+	"landlock_restrict_self":  unix.SYS_LANDLOCK_RESTRICT_SELF,
+	"process_mrelease":        unix.SYS_PROCESS_MRELEASE,
+	"futex_waitv":             unix.SYS_FUTEX_WAITV,
+	"set_mempolicy_home_node": unix.SYS_SET_MEMPOLICY_HOME_NODE,
+}
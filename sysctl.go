@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// namespacedSysctls tells which namespace a sysctl belongs to, keys ending in
+// a dot are prefixes. Only these can be set per container.
+var namespacedSysctls = map[string]string{
+	"kernel.domainname":      "uts",
+	"kernel.msgmax":          "ipc",
+	"kernel.msgmnb":          "ipc",
+	"kernel.msgmni":          "ipc",
+	"kernel.sem":             "ipc",
+	"kernel.shmall":          "ipc",
+	"kernel.shmmax":          "ipc",
+	"kernel.shmmni":          "ipc",
+	"kernel.shm_rmid_forced": "ipc",
+	"fs.mqueue.":             "ipc",
+	"net.":                   "net",
+}
+
+func getSysctlNamespace(key string) (string, bool) {
+	if namespace, ok := namespacedSysctls[key]; ok {
+		return namespace, true
+	}
+	for prefix, namespace := range namespacedSysctls {
+		if strings.HasSuffix(prefix, ".") && strings.HasPrefix(key, prefix) {
+			return namespace, true
+		}
+	}
+	return "", false
+}
+
+// getSysctlPath returns the /proc/sys file of a sysctl given in dotted or in
+// slash form. Every component must be a plain name and the file must be one of
+// the namespaced sysctls, so that a key like net/../kernel/core_pattern can not
+// reach a host-wide one.
+func getSysctlPath(key string) (string, error) {
+	separator := "."
+	if strings.Contains(key, "/") {
+		separator = "/"
+	}
+	components := strings.Split(key, separator)
+	for _, component := range components {
+		if len(component) == 0 || component == "." || component == ".." {
+			return "", fmt.Errorf("invalid sysctl: %s", key)
+		}
+	}
+	if _, ok := getSysctlNamespace(strings.Join(components, ".")); !ok {
+		return "", fmt.Errorf("sysctl %s is not namespaced and can not be set for a container", key)
+	}
+	path := filepath.Join(append([]string{"/proc/sys"}, components...)...)
+	for name := range namespacedSysctls {
+		allowed := filepath.Join("/proc/sys", strings.ReplaceAll(strings.TrimSuffix(name, "."), ".", "/"))
+		if path == allowed && !strings.HasSuffix(name, ".") {
+			return path, nil
+		}
+		if strings.HasSuffix(name, ".") && strings.HasPrefix(path, allowed+"/") {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("sysctl %s is not namespaced and can not be set for a container", key)
+}
+
+// parseSysctlOptions parses the key=value pairs given with --sysctl, keys may
+// also use slashes as in their /proc/sys path. Those are kept as given, since
+// a component like an interface name can hold dots itself.
+func parseSysctlOptions(opts []string) (map[string]string, error) {
+	sysctls := make(map[string]string)
+	for _, opt := range opts {
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok || len(key) == 0 {
+			return nil, fmt.Errorf("invalid sysctl: %s", opt)
+		}
+		if strings.ReplaceAll(key, "/", ".") == "kernel.hostname" {
+			return nil, fmt.Errorf("sysctl %s is set with --hostname", key)
+		}
+		if _, err := getSysctlPath(key); err != nil {
+			return nil, err
+		}
+		sysctls[key] = value
+	}
+	return sysctls, nil
+}
+
+// writeSysctls sets sysctls through the container /proc, it runs once the
+// container namespaces are joined and before /proc/sys is made read-only.
+func writeSysctls(sysctls map[string]string) error {
+	var keys []string
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path, err := getSysctlPath(key)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(sysctls[key]), 0644); err != nil {
+			return fmt.Errorf("set %s failed: %v", key, err)
+		}
+	}
+	return nil
+}
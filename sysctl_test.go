@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSysctlOptions(t *testing.T) {
+	tests := []struct {
+		opt  string
+		path string
+	}{
+		{"net.core.somaxconn=1024", "/proc/sys/net/core/somaxconn"},
+		{"net/ipv4/conf/eth0.100/forwarding=1", "/proc/sys/net/ipv4/conf/eth0.100/forwarding"},
+		{"kernel.sem=250 32000 100 128", "/proc/sys/kernel/sem"},
+		{"kernel/shmmax=1024", "/proc/sys/kernel/shmmax"},
+		{"fs.mqueue.msg_max=64", "/proc/sys/fs/mqueue/msg_max"},
+		{"kernel.domainname=example.com", "/proc/sys/kernel/domainname"},
+		{"net/../kernel/core_pattern=|/tmp/x", ""},
+		{"net/./../kernel/core_pattern=x", ""},
+		{"net/ipv4/../../kernel/core_pattern=x", ""},
+		{"fs.mqueue/../../kernel/core_pattern=x", ""},
+		{"net//ipv4/ip_forward=1", ""},
+		{"/net/ipv4/ip_forward=1", ""},
+		{"net/ipv4/ip_forward/=1", ""},
+		{"net..ipv4.ip_forward=1", ""},
+		{"net.=1", ""},
+		{"net=1", ""},
+		{"kernel.sem.x=1", ""},
+		{"kernel/sem/..=1", ""},
+		{"kernel.core_pattern=x", ""},
+		{"kernel.hostname=x", ""},
+		{"kernel/hostname=x", ""},
+		{"net.core.somaxconn", ""},
+		{"=1", ""},
+	}
+	for _, test := range tests {
+		sysctls, err := parseSysctlOptions([]string{test.opt})
+		if len(test.path) == 0 {
+			if err == nil {
+				t.Errorf("%q was accepted as %v", test.opt, sysctls)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q was rejected: %v", test.opt, err)
+			continue
+		}
+		for key := range sysctls {
+			if path, err := getSysctlPath(key); err != nil || path != test.path {
+				t.Errorf("%q resolves to %q (%v), want %q", test.opt, path, err, test.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+)
+
+// consoleSocketFd is the descriptor child-mode receives the console socket
+// on, it follows the sync pipe in cmd.ExtraFiles.
+const consoleSocketFd = 4
+
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	return err == nil
+}
+
+// openPty allocates a pseudo-terminal from the devpts instance behind
+// /dev/ptmx and returns its master and slave ends.
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := unix.IoctlSetPointerInt(int(master.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	ptyNum, err := unix.IoctlGetInt(int(master.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(ptyNum), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+func newConsoleSocketPair() (*os.File, *os.File, error) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return os.NewFile(uintptr(fds[0]), "console-socket"), os.NewFile(uintptr(fds[1]), "console-socket"), nil
+}
+
+func sendFd(socket *os.File, file *os.File) error {
+	return unix.Sendmsg(int(socket.Fd()), []byte(file.Name()), unix.UnixRights(int(file.Fd())), nil, 0)
+}
+
+func receiveFd(socket *os.File) (*os.File, error) {
+	name := make([]byte, 4096)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := unix.Recvmsg(int(socket.Fd()), name, oob, unix.MSG_CMSG_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	if oobn == 0 {
+		return nil, fmt.Errorf("console socket closed without a file descriptor")
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 {
+		return nil, fmt.Errorf("expected one control message, got %d", len(msgs))
+	}
+	fds, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(fds) != 1 {
+		return nil, fmt.Errorf("expected one file descriptor, got %d", len(fds))
+	}
+	return os.NewFile(uintptr(fds[0]), string(name[:n])), nil
+}
+
+// setupContainerConsole allocates the container's pseudo-terminal, hands the
+// master end to the supervisor over socket and makes the slave end the
+// controlling terminal and stdio of the calling process, which must already
+// lead its own session.
+func setupContainerConsole(socket *os.File, uid int) error {
+	defer socket.Close()
+	master, slave, err := openPty()
+	if err != nil {
+		return err
+	}
+	defer slave.Close()
+	err = sendFd(socket, master)
+	master.Close()
+	if err != nil {
+		return err
+	}
+	if err := slave.Chown(uid, -1); err != nil {
+		return err
+	}
+	if err := unix.IoctlSetInt(int(slave.Fd()), unix.TIOCSCTTY, 0); err != nil {
+		return err
+	}
+	for fd := 0; fd < 3; fd++ {
+		if err := unix.Dup2(int(slave.Fd()), fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func makeRawTerminal(fd int) (*unix.Termios, error) {
+	state, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+	raw := *state
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func resizePty(master *os.File, fd int) error {
+	size, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return err
+	}
+	return unix.IoctlSetWinsize(int(master.Fd()), unix.TIOCSWINSZ, size)
+}
+
+// attachTerminal connects the host terminal to the pty master: the pty
+// follows the window size of stdout and, when interactive, stdin is switched
+// to raw mode and copied to the pty. The returned function restores the host
+// terminal.
+func attachTerminal(master *os.File, interactive bool) func() {
+	stdoutFd := int(os.Stdout.Fd())
+	stdinFd := int(os.Stdin.Fd())
+	resizePty(master, stdoutFd)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, unix.SIGWINCH)
+	go func() {
+		for range sigCh {
+			resizePty(master, stdoutFd)
+		}
+	}()
+	var state *unix.Termios
+	if interactive {
+		if isTerminal(stdinFd) {
+			state, _ = makeRawTerminal(stdinFd)
+		}
+		go io.Copy(master, os.Stdin)
+	}
+	return func() {
+		signal.Stop(sigCh)
+		close(sigCh)
+		if state != nil {
+			unix.IoctlSetTermios(stdinFd, unix.TCSETS, state)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type containerUser struct {
+	Uid    int
+	Gid    int
+	Groups []int
+	Home   string
+}
+
+type passwdEntry struct {
+	name string
+	uid  int
+	gid  int
+	home string
+}
+
+type groupEntry struct {
+	name    string
+	gid     int
+	members []string
+}
+
+func readColonFile(path string, fields int) ([][]string, error) {
+	var entries [][]string
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return entries, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) < fields {
+			continue
+		}
+		entries = append(entries, parts)
+	}
+	return entries, scanner.Err()
+}
+
+func parsePasswdFile(path string) ([]passwdEntry, error) {
+	var users []passwdEntry
+	entries, err := readColonFile(path, 6)
+	if err != nil {
+		return users, err
+	}
+	for _, parts := range entries {
+		uid, uidErr := strconv.Atoi(parts[2])
+		gid, gidErr := strconv.Atoi(parts[3])
+		if uidErr != nil || gidErr != nil {
+			continue
+		}
+		users = append(users, passwdEntry{name: parts[0], uid: uid, gid: gid, home: parts[5]})
+	}
+	return users, nil
+}
+
+func parseGroupFile(path string) ([]groupEntry, error) {
+	var groups []groupEntry
+	entries, err := readColonFile(path, 4)
+	if err != nil {
+		return groups, err
+	}
+	for _, parts := range entries {
+		gid, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		var members []string
+		if len(parts[3]) > 0 {
+			members = strings.Split(parts[3], ",")
+		}
+		groups = append(groups, groupEntry{name: parts[0], gid: gid, members: members})
+	}
+	return groups, nil
+}
+
+// resolveContainerUser resolves a user[:group] spec against the /etc/passwd
+// and /etc/group of the current root, so it must run after the chroot.
+func resolveContainerUser(spec string) (containerUser, error) {
+	user := containerUser{Home: "/"}
+	userSpec, groupSpec := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		userSpec, groupSpec = spec[:i], spec[i+1:]
+	}
+	if len(userSpec) == 0 {
+		userSpec = "0"
+	}
+
+	users, err := parsePasswdFile("/etc/passwd")
+	if err != nil {
+		return user, err
+	}
+	groups, err := parseGroupFile("/etc/group")
+	if err != nil {
+		return user, err
+	}
+
+	uid, uidErr := strconv.Atoi(userSpec)
+	userName := ""
+	found := false
+	for _, entry := range users {
+		if (uidErr == nil && entry.uid == uid) || (uidErr != nil && entry.name == userSpec) {
+			user.Uid, user.Gid, user.Home = entry.uid, entry.gid, entry.home
+			userName = entry.name
+			found = true
+			break
+		}
+	}
+	if !found {
+		if uidErr != nil {
+			return user, fmt.Errorf("unable to find user %s: no matching entries in passwd file", userSpec)
+		}
+		user.Uid = uid
+	}
+
+	if len(groupSpec) > 0 {
+		gid, gidErr := strconv.Atoi(groupSpec)
+		if gidErr != nil {
+			found = false
+			for _, entry := range groups {
+				if entry.name == groupSpec {
+					gid = entry.gid
+					found = true
+					break
+				}
+			}
+			if !found {
+				return user, fmt.Errorf("unable to find group %s: no matching entries in group file", groupSpec)
+			}
+		}
+		user.Gid = gid
+	}
+
+	if len(userName) > 0 {
+		for _, entry := range groups {
+			if entry.gid != user.Gid && stringInSlice(userName, entry.members) {
+				user.Groups = append(user.Groups, entry.gid)
+			}
+		}
+	}
+	return user, nil
+}
+
+func toUint32s(ints []int) []uint32 {
+	var result []uint32
+	for _, i := range ints {
+		result = append(result, uint32(i))
+	}
+	return result
+}
+
+func getContainerUserSpec(userFlag string, imgConfig imageConfig) string {
+	if len(userFlag) > 0 {
+		return userFlag
+	}
+	return imgConfig.Config.User
+}
+
+func addUserHomeEnv(env []string, user containerUser) []string {
+	if _, ok := getEnvValue(env, "HOME"); ok {
+		return env
+	}
+	return append(env, "HOME="+user.Home)
+}
+
+// switchContainerUser changes the credentials of the calling thread, it is
+// meant to run right before exec on the thread that is going to exec.
+func switchContainerUser(user containerUser) error {
+	// setgroups is denied where only the rootless user is mapped
+	if len(user.Groups) > 0 || !isSetgroupsDenied() {
+		if err := unix.Setgroups(user.Groups); err != nil {
+			return err
+		}
+	}
+	if err := unix.Setgid(user.Gid); err != nil {
+		return err
+	}
+	return unix.Setuid(user.Uid)
+}
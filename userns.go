@@ -0,0 +1,336 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// defaultRemapUser is the subordinate id owner used by --userns-remap default
+const defaultRemapUser = "gocker"
+
+type idMapping struct {
+	ContainerId int `json:"containerId"`
+	HostId      int `json:"hostId"`
+	Size        int `json:"size"`
+}
+
+// parseIdMapping parses a --uidmap or --gidmap value of the form
+// <container-id>:<host-id>:<size>
+func parseIdMapping(spec string) (idMapping, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		return idMapping{}, fmt.Errorf("invalid id mapping: %s", spec)
+	}
+	var ids [3]int
+	for i, part := range parts {
+		id, err := strconv.Atoi(part)
+		if err != nil || id < 0 {
+			return idMapping{}, fmt.Errorf("invalid id mapping: %s", spec)
+		}
+		ids[i] = id
+	}
+	if ids[2] == 0 {
+		return idMapping{}, fmt.Errorf("invalid id mapping size: %s", spec)
+	}
+	return idMapping{ContainerId: ids[0], HostId: ids[1], Size: ids[2]}, nil
+}
+
+// parseSubordinateIds reads the ranges /etc/subuid or /etc/subgid give to
+// user, by name or by uid in both files, and maps them one after another from
+// container id 0.
+func parseSubordinateIds(path string, user string, userId string) ([]idMapping, error) {
+	lines, err := readColonFile(path, 3)
+	if err != nil {
+		return nil, err
+	}
+	var mappings []idMapping
+	containerId := 0
+	for _, fields := range lines {
+		if fields[0] != user && fields[0] != userId {
+			continue
+		}
+		start, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range for %s in %s", user, path)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid range for %s in %s", user, path)
+		}
+		mappings = append(mappings, idMapping{ContainerId: containerId, HostId: start, Size: count})
+		containerId += count
+	}
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("no subordinate ids for %s in %s", user, path)
+	}
+	return mappings, nil
+}
+
+// getRemapUser resolves a --userns-remap user to its name and uid, which
+// /etc/subuid and /etc/subgid entries are keyed by
+func getRemapUser(remap string) (string, string) {
+	if remap == "default" {
+		remap = defaultRemapUser
+	}
+	users, err := parsePasswdFile("/etc/passwd")
+	if err != nil {
+		return remap, remap
+	}
+	for _, user := range users {
+		if user.name == remap || strconv.Itoa(user.uid) == remap {
+			return user.name, strconv.Itoa(user.uid)
+		}
+	}
+	return remap, remap
+}
+
+// getUserNamespaceMappings resolves --userns-remap or --uidmap/--gidmap to the
+// id mappings of the container. Without any of them the container shares the
+// host user namespace and nil mappings are returned.
+func getUserNamespaceMappings(remap string, uidMaps []string, gidMaps []string) ([]idMapping, []idMapping, error) {
+	if len(remap) > 0 && (len(uidMaps) > 0 || len(gidMaps) > 0) {
+		return nil, nil, fmt.Errorf("--userns-remap can not be combined with --uidmap or --gidmap")
+	}
+	var uidMappings, gidMappings []idMapping
+	if len(remap) > 0 {
+		name, uid := getRemapUser(remap)
+		var err error
+		if uidMappings, err = parseSubordinateIds("/etc/subuid", name, uid); err != nil {
+			return nil, nil, err
+		}
+		if gidMappings, err = parseSubordinateIds("/etc/subgid", name, uid); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, spec := range uidMaps {
+		mapping, err := parseIdMapping(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		uidMappings = append(uidMappings, mapping)
+	}
+	for _, spec := range gidMaps {
+		mapping, err := parseIdMapping(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		gidMappings = append(gidMappings, mapping)
+	}
+	if len(uidMappings) > 0 && len(gidMappings) == 0 {
+		gidMappings = uidMappings
+	}
+	if len(gidMappings) > 0 && len(uidMappings) == 0 {
+		uidMappings = gidMappings
+	}
+	if len(uidMappings) > 0 {
+		if _, ok := getHostId(uidMappings, 0); !ok {
+			return nil, nil, fmt.Errorf("the uid mappings must map the container root")
+		}
+		if _, ok := getHostId(gidMappings, 0); !ok {
+			return nil, nil, fmt.Errorf("the gid mappings must map the container root")
+		}
+	}
+	return uidMappings, gidMappings, nil
+}
+
+func getHostId(mappings []idMapping, id int) (int, bool) {
+	for _, mapping := range mappings {
+		if id >= mapping.ContainerId && id < mapping.ContainerId+mapping.Size {
+			return mapping.HostId + id - mapping.ContainerId, true
+		}
+	}
+	return 0, false
+}
+
+// shiftId maps a container id to the host, ids are kept when there are no
+// mappings. An unmapped id is an error rather than an id of the host.
+func shiftId(mappings []idMapping, id int) (int, error) {
+	if len(mappings) == 0 {
+		return id, nil
+	}
+	if hostId, ok := getHostId(mappings, id); ok {
+		return hostId, nil
+	}
+	return 0, fmt.Errorf("id %d is not mapped in the user namespace", id)
+}
+
+func shiftOwner(uidMappings []idMapping, gidMappings []idMapping, uid int, gid int) (int, int, error) {
+	hostUid, err := shiftId(uidMappings, uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	hostGid, err := shiftId(gidMappings, gid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return hostUid, hostGid, nil
+}
+
+func toSysProcIDMaps(mappings []idMapping) []syscall.SysProcIDMap {
+	var idMaps []syscall.SysProcIDMap
+	for _, mapping := range mappings {
+		idMaps = append(idMaps, syscall.SysProcIDMap{
+			ContainerID: mapping.ContainerId,
+			HostID:      mapping.HostId,
+			Size:        mapping.Size,
+		})
+	}
+	return idMaps
+}
+
+// setUserNamespaceAttr makes cmd start in a new user namespace with the given
+// mappings, running as its root.
+func setUserNamespaceAttr(attr *unix.SysProcAttr, uidMappings []idMapping, gidMappings []idMapping) {
+	attr.Cloneflags |= unix.CLONE_NEWUSER
+	attr.UidMappings = toSysProcIDMaps(uidMappings)
+	attr.GidMappings = toSysProcIDMaps(gidMappings)
+	attr.GidMappingsEnableSetgroups = true
+	attr.Credential = &syscall.Credential{Uid: 0, Gid: 0}
+}
+
+func getIdMappingsKey(uidMappings []idMapping, gidMappings []idMapping) string {
+	data, _ := json.Marshal([][]idMapping{uidMappings, gidMappings})
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:12]
+}
+
+// copyShiftedTree copies the file tree at src to dst, shifting the owner of
+// every entry through the mappings. Hard links are kept.
+func copyShiftedTree(src string, dst string, uidMappings []idMapping, gidMappings []idMapping) error {
+	links := make(map[uint64]string)
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("stat %s failed", path)
+		}
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.Mkdir(target, mode.Perm()); err != nil && !os.IsExist(err) {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case mode.IsRegular() && stat.Nlink > 1 && len(links[stat.Ino]) > 0:
+			return os.Link(links[stat.Ino], target)
+		case mode.IsRegular():
+			if err := copyRegularFile(path, target, mode.Perm()); err != nil {
+				return err
+			}
+			links[stat.Ino] = target
+		default:
+			if err := unix.Mknod(target, stat.Mode, int(stat.Rdev)); err != nil {
+				return err
+			}
+		}
+		uid, gid, err := shiftOwner(uidMappings, gidMappings, int(stat.Uid), int(stat.Gid))
+		if err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		}
+		if err := os.Lchown(target, uid, gid); err != nil {
+			return err
+		}
+		// chown clears the setuid and setgid bits
+		if mode&os.ModeSymlink == 0 {
+			return unix.Chmod(target, stat.Mode&07777)
+		}
+		return nil
+	})
+}
+
+func copyRegularFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// getShiftedLayerPath returns a copy of the layer owned by the mapped ids,
+// creating it on first use. Copies live next to the layer so rmi removes them.
+func getShiftedLayerPath(layerPath string, uidMappings []idMapping, gidMappings []idMapping) (string, error) {
+	shiftedPath := layerPath + "-" + getIdMappingsKey(uidMappings, gidMappings)
+	if _, err := os.Stat(shiftedPath); err == nil {
+		return shiftedPath, nil
+	}
+	tmpPath, err := os.MkdirTemp(filepath.Dir(layerPath), ".shift-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(tmpPath); err != nil {
+		return "", err
+	}
+	if err := copyShiftedTree(layerPath, tmpPath, uidMappings, gidMappings); err != nil {
+		os.RemoveAll(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, shiftedPath); err != nil {
+		// another container created the same copy meanwhile
+		os.RemoveAll(tmpPath)
+		if _, statErr := os.Stat(shiftedPath); statErr != nil {
+			return "", err
+		}
+	}
+	return shiftedPath, nil
+}
+
+// shiftVolumeOwners hands the empty volumes of a container to its root, so it
+// can populate them from the image.
+func shiftVolumeOwners(mounts []mountConfig, uidMappings []idMapping, gidMappings []idMapping) error {
+	for _, mount := range mounts {
+		if mount.Type != mountTypeVolume {
+			continue
+		}
+		dataPath := getVolumeDataPath(mount.Source)
+		empty, err := isDirEmpty(dataPath)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			continue
+		}
+		uid, gid, err := shiftOwner(uidMappings, gidMappings, 0, 0)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(dataPath, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isSetgroupsDenied() bool {
+	data, err := os.ReadFile("/proc/self/setgroups")
+	return err == nil && strings.TrimSpace(string(data)) == "deny"
+}
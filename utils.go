@@ -6,12 +6,21 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/exec"
+	"syscall"
 )
 
 const (
+	exitCodeCommandNotRunnable = 126
+	exitCodeCommandNotFound    = 127
+)
+
+// the paths are moved under the user's directories in rootless mode
+var (
 	gockerHomePath       = "/var/lib/gocker"
 	gockerImagesPath     = gockerHomePath + "/images"
 	gockerTempPath       = gockerHomePath + "/tmp"
+	gockerVolumesPath    = gockerHomePath + "/volumes"
 	gockerContainersPath = "/var/run/gocker/containers"
 	gockerNetNsPath      = "/var/run/gocker/net-ns"
 )
@@ -19,15 +28,23 @@ const (
 func usage() {
 	fmt.Println("Welcome to gocker!")
 	fmt.Println("Supported commands:")
-	fmt.Println("gocker run [--mem] [--swap] [--pids] [--cpus] <image> <commands>")
-	fmt.Println("gocker exec <container-id> <commands>")
+	fmt.Println("gocker run [-d] [-i] [-t] [--rm] [--init] [--entrypoint] [-e] [--env-file] [-u] [--name] [-h hostname] [-v] [--mount] [--tmpfs] [--shm-size] [--read-only] [--rootfs-propagation] [--cap-add] [--cap-drop] [--privileged] [--security-opt] [--ulimit] [--oom-score-adj] [--sysctl] [--userns-remap] [--uidmap] [--gidmap] [--mem] [--swap] [--pids] [--cpus] <image> [commands]")
+	fmt.Println("gocker exec [-i] [-t] [-e] [--env-file] [-u] <container> <commands>")
+	fmt.Println("gocker stop [-t seconds] <container>")
+	fmt.Println("gocker kill [-s signal] <container>")
+	fmt.Println("gocker inspect <container>")
+	fmt.Println("gocker volume create|ls|inspect|rm|prune [name]")
 	fmt.Println("gocker images")
-	fmt.Println("gocker ps")
+	fmt.Println("gocker start [-a] <container>")
+	fmt.Println("gocker rm [-f] <container>")
+	fmt.Println("gocker wait <container>")
+	fmt.Println("gocker logs [-f] [--since] [--tail] <container>")
+	fmt.Println("gocker ps [-a]")
 	fmt.Println("gocker rmi <image-id>")
 }
 
 func initGockerDirs() error {
-	dirs := []string{gockerHomePath, gockerTempPath, gockerImagesPath, gockerContainersPath}
+	dirs := []string{gockerHomePath, gockerTempPath, gockerImagesPath, gockerContainersPath, gockerVolumesPath}
 	return createDirsIfNotExist(dirs)
 }
 
@@ -109,3 +126,22 @@ func getGockerContainersPath() string {
 func getGockerNetNsPath() string {
 	return gockerNetNsPath
 }
+
+func getExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return getWaitStatusExitCode(status)
+		}
+	}
+	return 1
+}
+
+func getWaitStatusExitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
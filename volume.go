@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type volumeInfo struct {
+	Name       string    `json:"name"`
+	Mountpoint string    `json:"mountpoint"`
+	Anonymous  bool      `json:"anonymous"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func getGockerVolumesPath() string {
+	return gockerVolumesPath
+}
+
+func getVolumeHome(name string) string {
+	return getGockerVolumesPath() + "/" + name
+}
+
+func getVolumeDataPath(name string) string {
+	return getVolumeHome(name) + "/_data"
+}
+
+func getVolumeInfoPath(name string) string {
+	return getVolumeHome(name) + "/volume.json"
+}
+
+func createVolumeName() string {
+	randBytes := make([]byte, 32)
+	rand.Read(randBytes)
+	return hex.EncodeToString(randBytes)
+}
+
+func loadVolumeInfo(name string) (volumeInfo, error) {
+	volume := volumeInfo{}
+	data, err := os.ReadFile(getVolumeInfoPath(name))
+	if err != nil {
+		return volume, err
+	}
+	if err := json.Unmarshal(data, &volume); err != nil {
+		return volume, err
+	}
+	return volume, nil
+}
+
+func getVolumes() ([]volumeInfo, error) {
+	var volumes []volumeInfo
+	entries, err := os.ReadDir(getGockerVolumesPath())
+	if err != nil {
+		return volumes, err
+	}
+	for _, entry := range entries {
+		if volume, err := loadVolumeInfo(entry.Name()); err == nil {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes, nil
+}
+
+// validateVolumeName runs before a name is joined into the volumes path, so
+// that it can not point outside of it.
+func validateVolumeName(name string) error {
+	if !validContainerName.MatchString(name) {
+		return fmt.Errorf("invalid volume name %q, only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	return nil
+}
+
+// createVolume creates the named volume, or returns the existing one.
+func createVolume(name string, anonymous bool) (volumeInfo, error) {
+	if err := validateVolumeName(name); err != nil {
+		return volumeInfo{}, err
+	}
+	if volume, err := loadVolumeInfo(name); err == nil {
+		return volume, nil
+	}
+	volume := volumeInfo{
+		Name:       name,
+		Mountpoint: getVolumeDataPath(name),
+		Anonymous:  anonymous,
+		CreatedAt:  time.Now(),
+	}
+	if err := createDirsIfNotExist([]string{volume.Mountpoint}); err != nil {
+		return volume, err
+	}
+	data, err := json.Marshal(volume)
+	if err != nil {
+		return volume, err
+	}
+	return volume, os.WriteFile(getVolumeInfoPath(name), data, 0644)
+}
+
+func getVolumeUsers(name string) []string {
+	var users []string
+	states, _ := getContainerStates()
+	for _, state := range states {
+		config, err := loadContainerConfig(state.Id)
+		if err != nil {
+			continue
+		}
+		for _, mount := range config.Mounts {
+			if mount.Type == mountTypeVolume && mount.Source == name {
+				users = append(users, state.Id)
+				break
+			}
+		}
+	}
+	return users
+}
+
+func removeVolume(name string, force bool) error {
+	if err := validateVolumeName(name); err != nil {
+		return err
+	}
+	if _, err := loadVolumeInfo(name); err != nil {
+		return fmt.Errorf("no such volume: %s", name)
+	}
+	if users := getVolumeUsers(name); len(users) > 0 && !force {
+		return fmt.Errorf("volume %s is in use by container %s", name, users[0])
+	}
+	return removeAll(getVolumeHome(name))
+}
+
+func removeAnonymousVolumes(mounts []mountConfig) {
+	for _, mount := range mounts {
+		if mount.Type != mountTypeVolume {
+			continue
+		}
+		if volume, err := loadVolumeInfo(mount.Source); err == nil && volume.Anonymous {
+			if err := removeVolume(volume.Name, false); err != nil {
+				log.Printf("remove volume %s failed: %v\n", volume.Name, err)
+			}
+		}
+	}
+}
+
+// createContainerVolumes creates the named volumes the container mounts, and
+// anonymous volumes for the image volumes that nothing is mounted on.
+func createContainerVolumes(mounts []mountConfig, imgConfig imageConfig) ([]mountConfig, error) {
+	targets := map[string]bool{}
+	for i, mount := range mounts {
+		targets[filepath.Clean(mount.Target)] = true
+		if mount.Type != mountTypeVolume {
+			continue
+		}
+		anonymous := len(mount.Source) == 0
+		if anonymous {
+			mounts[i].Source = createVolumeName()
+		}
+		if _, err := createVolume(mounts[i].Source, anonymous); err != nil {
+			return nil, err
+		}
+	}
+	for path := range imgConfig.Config.Volumes {
+		if targets[filepath.Clean(path)] {
+			continue
+		}
+		volume, err := createVolume(createVolumeName(), true)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, mountConfig{Type: mountTypeVolume, Source: volume.Name, Target: path})
+	}
+	return mounts, nil
+}
+
+func isDirEmpty(path string) (bool, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer dir.Close()
+	_, err = dir.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// copyDirContents copies the tree under src into dst keeping modes, owners
+// and symlinks, which is how an empty volume is populated from the image.
+func copyDirContents(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		stat, _ := info.Sys().(*unix.Stat_t)
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, info.Mode()&os.ModePerm|info.Mode()&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+				return err
+			}
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := copyFile(path, target); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, info.Mode()); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		if stat != nil {
+			return os.Lchown(target, int(stat.Uid), int(stat.Gid))
+		}
+		return nil
+	})
+}
+
+// populateVolume copies the image content at the mount target into the
+// volume the first time an empty volume is mounted there.
+func populateVolume(rootfs string, mount mountConfig) error {
+	dataPath := getVolumeDataPath(mount.Source)
+	empty, err := isDirEmpty(dataPath)
+	if err != nil || !empty {
+		return err
+	}
+	imagePath, err := secureJoin(rootfs, mount.Target)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(imagePath); err != nil || !info.IsDir() {
+		return nil
+	}
+	return copyDirContents(imagePath, dataPath)
+}
+
+func printVolumes() {
+	volumes, err := getVolumes()
+	if err != nil {
+		log.Fatalln("get volumes failed")
+	}
+	fmt.Println("VOLUME NAME")
+	for _, volume := range volumes {
+		fmt.Println(volume.Name)
+	}
+}
+
+func inspectVolume(name string) {
+	if err := validateVolumeName(name); err != nil {
+		log.Fatalln(err)
+	}
+	volume, err := loadVolumeInfo(name)
+	if err != nil {
+		log.Fatalf("no such volume: %s\n", name)
+	}
+	data, err := json.MarshalIndent(volume, "", "  ")
+	doOrDieWithMsg(err, "marshal volume failed")
+	fmt.Println(string(data))
+}
+
+func pruneVolumes() {
+	volumes, err := getVolumes()
+	if err != nil {
+		log.Fatalln("get volumes failed")
+	}
+	for _, volume := range volumes {
+		if len(getVolumeUsers(volume.Name)) > 0 {
+			continue
+		}
+		if err := removeVolume(volume.Name, false); err != nil {
+			log.Printf("remove volume %s failed: %v\n", volume.Name, err)
+			continue
+		}
+		fmt.Println(volume.Name)
+	}
+}
+
+func runVolumeCommand(args []string) {
+	if len(args) < 1 {
+		usage()
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "create":
+		name := createVolumeName()
+		if len(args) > 1 {
+			name = args[1]
+		}
+		volume, err := createVolume(name, false)
+		doOrDieWithMsg(err, "create volume failed")
+		fmt.Println(volume.Name)
+	case "ls":
+		printVolumes()
+	case "inspect":
+		if len(args) < 2 {
+			log.Fatalln("volume name is needed")
+		}
+		inspectVolume(args[1])
+	case "rm":
+		if len(args) < 2 {
+			log.Fatalln("volume name is needed")
+		}
+		for _, name := range args[1:] {
+			doOrDieWithMsg(removeVolume(name, false), "remove volume failed")
+			fmt.Println(name)
+		}
+	case "prune":
+		pruneVolumes()
+	default:
+		usage()
+		os.Exit(1)
+	}
+}